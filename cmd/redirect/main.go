@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log"
+	"net"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/redirect"
+	"github.com/Liphium/hytale-matchmaking/starter"
+	"github.com/joho/godotenv"
+)
+
+// How long players that are still waiting get for their redirect when shutting down
+const ShutdownTimeout = 10 * time.Second
+
+// Runs the matchmaker together with a redirect listener that sends every player that connects to a match.
+//
+// The listener only speaks the line protocol from redirect/protocol.go (a stub for proxies and testing), Hytale clients
+// can't connect to it directly until the real handshake and redirect are implemented behind redirect.Protocol.
+func main() {
+	godotenv.Load()
+	config := redirect.LoadConfig()
+
+	listener, err := net.Listen("tcp", config.Listen)
+	if err != nil {
+		log.Fatalln("Couldn't start redirect server:", err)
+	}
+	server := redirect.NewServer(config, redirect.LineProtocol{}, redirect.SignedLogins{Secret: config.Secret})
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			log.Fatalln("Redirect server stopped:", err)
+		}
+	}()
+	log.Println("Redirecting players on", listener.Addr(), "to", config.Game, "(line protocol stub, not the Hytale protocol)")
+
+	// The matchmaker itself (game servers still need the API), players still waiting get some time before it stops
+	starter.StartWithShutdown(func() {
+		server.Shutdown(ShutdownTimeout)
+	})
+}
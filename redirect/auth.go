@@ -0,0 +1,55 @@
+package redirect
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Checks that a client really is the account it claims to be (nobody is queued before this)
+type Authenticator interface {
+	Authenticate(handshake Handshake) bool
+}
+
+// Logins signed by whatever authenticated the player before they connect (e.g. a proxy or the login service of the network)
+//
+// A login looks like <expires>.<signature>, the signature being an HMAC-SHA256 of "<account>.<expires>" using the shared secret.
+type SignedLogins struct {
+	Secret string
+}
+
+func (sl SignedLogins) Authenticate(handshake Handshake) bool {
+	if sl.Secret == "" {
+		return false
+	}
+
+	expires, signature, found := strings.Cut(handshake.Login, ".")
+	if !found {
+		return false
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() >= unix {
+		return false
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(decoded, signLogin(sl.Secret, handshake.Account, expires))
+}
+
+// Create a login for an account (for whatever authenticates players before they connect)
+func SignLogin(secret string, account string, expires time.Time) string {
+	unix := strconv.FormatInt(expires.Unix(), 10)
+	return unix + "." + base64.RawURLEncoding.EncodeToString(signLogin(secret, account, unix))
+}
+
+// Helper function for signing the account and expiry of a login
+func signLogin(secret string, account string, expires string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(account + "." + expires))
+	return mac.Sum(nil)
+}
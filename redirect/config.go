@@ -0,0 +1,58 @@
+package redirect
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	DefaultListen           = ":5520"
+	DefaultQueueTimeout     = 30 * time.Second // How long players wait for a match before they're told there isn't one
+	DefaultHandshakeTimeout = 5 * time.Second  // How long clients have for sending the handshake
+	QueuePollInterval       = 250 * time.Millisecond
+)
+
+type Config struct {
+	Listen           string        // Address the redirect server listens on
+	Game             string        // Game players are queued for when they connect
+	QueueTimeout     time.Duration // 0 to reject players right away in case there is no slot
+	HandshakeTimeout time.Duration
+	Secret           string // Used for checking the logins clients send (see auth.go)
+}
+
+// Load the config of the redirect server from the environment
+func LoadConfig() Config {
+	config := Config{
+		Listen:           DefaultListen,
+		Game:             os.Getenv("REDIRECT_GAME"),
+		QueueTimeout:     DefaultQueueTimeout,
+		HandshakeTimeout: DefaultHandshakeTimeout,
+		Secret:           os.Getenv("REDIRECT_SECRET"),
+	}
+	if config.Game == "" {
+		log.Fatalln("REDIRECT_GAME has to be set to the game players should be sent to")
+	}
+	if config.Secret == "" {
+		log.Fatalln("REDIRECT_SECRET has to be set so players can be authenticated")
+	}
+	if listen := os.Getenv("REDIRECT_LISTEN"); listen != "" {
+		config.Listen = listen
+	}
+	config.QueueTimeout = durationFromEnv("REDIRECT_QUEUE_TIMEOUT", config.QueueTimeout)
+	config.HandshakeTimeout = durationFromEnv("REDIRECT_HANDSHAKE_TIMEOUT", config.HandshakeTimeout)
+	return config
+}
+
+// Helper function for parsing a duration from the environment (fallback when it's not set)
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalln("Invalid "+name+":", err)
+	}
+	return duration
+}
@@ -0,0 +1,92 @@
+package redirect
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// What a client sends when connecting
+type Handshake struct {
+	Account string
+	Login   string // Proves the client is the account (checked by the Authenticator, see auth.go)
+}
+
+// Where a player is sent to
+type Destination struct {
+	Address string
+	Port    int
+	Token   string
+	Ticket  string // Signed join ticket (see service/tickets.go)
+}
+
+// Wire format spoken with clients (the real Hytale handshake and redirect aren't implemented yet, they can be plugged in here without touching the server)
+type Protocol interface {
+
+	// Read the handshake a client sends after connecting
+	ReadHandshake(r *bufio.Reader) (Handshake, error)
+
+	// Tell the client it's still in the queue (can be used as a keep-alive)
+	Wait(w io.Writer, position int) error
+
+	// Send the client to a server
+	Redirect(w io.Writer, destination Destination) error
+
+	// Tell the client it can't be sent anywhere
+	Reject(w io.Writer, reason string) error
+}
+
+// Reasons sent to clients that are rejected
+const (
+	ReasonInvalidHandshake = "invalid_handshake"
+	ReasonNotAuthenticated = "not_authenticated"
+	ReasonAlreadyPlaying   = "already_playing"
+	ReasonNoMatch          = "no_match_available"
+	ReasonShuttingDown     = "shutting_down"
+)
+
+var ErrInvalidHandshake = errors.New("invalid handshake")
+
+// Stub protocol, not what Hytale clients speak (used for testing and by proxies in front of the redirect server):
+//
+//	client: HELLO <account> <login>
+//	server: WAIT <position> (any number of times)
+//	server: REDIRECT <address> <port> <token> <ticket> or REJECT <reason>
+type LineProtocol struct{}
+
+func (LineProtocol) ReadHandshake(r *bufio.Reader) (Handshake, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return Handshake{}, err
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) != 3 || fields[0] != "HELLO" {
+		return Handshake{}, ErrInvalidHandshake
+	}
+	return Handshake{
+		Account: fields[1],
+		Login:   fields[2],
+	}, nil
+}
+
+func (LineProtocol) Wait(w io.Writer, position int) error {
+	_, err := fmt.Fprintf(w, "WAIT %d\n", position)
+	return err
+}
+
+func (LineProtocol) Redirect(w io.Writer, destination Destination) error {
+	ticket := destination.Ticket
+	if ticket == "" {
+		ticket = "-"
+	}
+	_, err := fmt.Fprintf(w, "REDIRECT %s %d %s %s\n", destination.Address, destination.Port, destination.Token, ticket)
+	return err
+}
+
+func (LineProtocol) Reject(w io.Writer, reason string) error {
+	_, err := fmt.Fprintf(w, "REJECT %s\n", reason)
+	return err
+}
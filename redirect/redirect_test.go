@@ -0,0 +1,172 @@
+package redirect_test
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/redirect"
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	serverId = 1
+	game     = "lobby"
+	secret   = "redirect-secret"
+)
+
+// Helper function for starting a redirect server on a random port
+func startRedirect(t *testing.T, timeout time.Duration) (*redirect.Server, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("couldn't listen:", err)
+	}
+
+	server := redirect.NewServer(redirect.Config{
+		Game:             game,
+		QueueTimeout:     timeout,
+		HandshakeTimeout: time.Second,
+	}, redirect.LineProtocol{}, redirect.SignedLogins{Secret: secret})
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Shutdown(time.Second) })
+
+	return server, listener.Addr().String()
+}
+
+// Fake client speaking the line protocol
+type fakeClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func connect(t *testing.T, address string, account string) *fakeClient {
+	return connectWithLogin(t, address, account, redirect.SignLogin(secret, account, time.Now().Add(time.Minute)))
+}
+
+func connectWithLogin(t *testing.T, address string, account string, login string) *fakeClient {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal("couldn't connect:", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if account != "" {
+		fmt.Fprintf(conn, "HELLO %s %s\n", account, login)
+	}
+	return &fakeClient{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+}
+
+// Read lines until the server sends something other than WAIT
+func (fc *fakeClient) result(t *testing.T) (waited bool, fields []string) {
+	fc.conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	for {
+		line, err := fc.reader.ReadString('\n')
+		if err != nil {
+			t.Fatal("couldn't read:", err)
+		}
+		fields = strings.Fields(line)
+		if fields[0] != "WAIT" {
+			return waited, fields
+		}
+		waited = true
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	service.ResetAll()
+	assert.True(t, service.CreateServer(serverId, "localhost", 3000))
+
+	_, address := startRedirect(t, 300*time.Millisecond)
+
+	t.Run("rejected when there is no match", func(t *testing.T) {
+		waited, fields := connect(t, address, "nobody").result(t)
+		assert.True(t, waited)
+		assert.Equal(t, []string{"REJECT", redirect.ReasonNoMatch}, fields)
+		assert.False(t, service.IsOnServerOrWaiting("nobody"))
+	})
+
+	t.Run("invalid handshakes are rejected", func(t *testing.T) {
+		client := connect(t, address, "")
+		fmt.Fprint(client.conn, "GET / HTTP/1.1\n")
+		_, fields := client.result(t)
+		assert.Equal(t, []string{"REJECT", redirect.ReasonInvalidHandshake}, fields)
+	})
+
+	assert.True(t, service.AddMatch(serverId, service.MatchCreate{
+		ID:   1,
+		Game: game,
+	}, []string{"a", "b"}))
+
+	t.Run("players have to prove who they are", func(t *testing.T) {
+		for _, login := range []string{
+			"-",
+			redirect.SignLogin("wrong-secret", "victim", time.Now().Add(time.Minute)),
+			redirect.SignLogin(secret, "someone-else", time.Now().Add(time.Minute)),
+			redirect.SignLogin(secret, "victim", time.Now().Add(-time.Second)),
+		} {
+			_, fields := connectWithLogin(t, address, "victim", login).result(t)
+			assert.Equal(t, []string{"REJECT", redirect.ReasonNotAuthenticated}, fields)
+		}
+		assert.False(t, service.IsOnServerOrWaiting("victim"))
+	})
+	assert.True(t, service.SetMatchState(serverId, 1, service.MatchStateAccepting))
+
+	t.Run("redirected to a match", func(t *testing.T) {
+		_, fields := connect(t, address, "player").result(t)
+		if assert.Len(t, fields, 5) {
+			assert.Equal(t, "REDIRECT", fields[0])
+			assert.Equal(t, "localhost", fields[1])
+			assert.Equal(t, "3000", fields[2])
+			assert.Contains(t, []string{"a", "b"}, fields[3])
+
+			audience, ok := service.GetTicketAudience(serverId)
+			assert.True(t, ok)
+			ticket, err := service.VerifyJoinTicket(fields[4], service.TicketPublicKey(), audience)
+			assert.Nil(t, err)
+			assert.Equal(t, "player", ticket.Account)
+			assert.Equal(t, fields[3], ticket.Token)
+		}
+	})
+
+	t.Run("players can't connect twice", func(t *testing.T) {
+		_, fields := connect(t, address, "player").result(t)
+		assert.Equal(t, []string{"REJECT", redirect.ReasonAlreadyPlaying}, fields)
+	})
+
+	t.Run("waiting players get the next free slot", func(t *testing.T) {
+		assert.True(t, service.SetMatchState(serverId, 1, service.MatchStateFull))
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			service.SetMatchState(serverId, 1, service.MatchStateAccepting)
+		}()
+
+		waited, fields := connect(t, address, "waiting").result(t)
+		assert.True(t, waited)
+		assert.Equal(t, "REDIRECT", fields[0])
+	})
+
+	t.Run("waiting players are told when shutting down", func(t *testing.T) {
+		assert.True(t, service.SetMatchState(serverId, 1, service.MatchStateFull))
+		slowServer, slow := startRedirect(t, time.Minute)
+		client := connect(t, slow, "late")
+
+		client.conn.SetReadDeadline(time.Now().Add(time.Second))
+		line, err := client.reader.ReadString('\n')
+		assert.Nil(t, err)
+		assert.Equal(t, "WAIT 1", strings.TrimSpace(line))
+
+		slowServer.Shutdown(time.Second)
+		_, fields := client.result(t)
+		assert.Equal(t, []string{"REJECT", redirect.ReasonShuttingDown}, fields)
+		assert.False(t, service.IsOnServerOrWaiting("late"))
+
+		_, err = net.Dial("tcp", slow)
+		assert.NotNil(t, err)
+	})
+}
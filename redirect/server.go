@@ -0,0 +1,179 @@
+package redirect
+
+import (
+	"bufio"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+)
+
+// Accepts player connections and sends them to a match using the queue of the matchmaker (in the same process)
+type Server struct {
+	config        Config
+	protocol      Protocol
+	authenticator Authenticator
+
+	mutex    *sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closing  chan struct{}
+	active   *sync.WaitGroup
+}
+
+func NewServer(config Config, protocol Protocol, authenticator Authenticator) *Server {
+	return &Server{
+		config:        config,
+		protocol:      protocol,
+		authenticator: authenticator,
+		mutex:         &sync.Mutex{},
+		conns:         map[net.Conn]struct{}{},
+		closing:       make(chan struct{}),
+		active:        &sync.WaitGroup{},
+	}
+}
+
+// Handle all connections on the listener (returns once the server has been shut down)
+func (s *Server) Serve(listener net.Listener) error {
+	s.mutex.Lock()
+	s.listener = listener
+	s.mutex.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-s.closing:
+				return nil
+			default:
+			}
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			return err
+		}
+
+		s.mutex.Lock()
+		s.conns[conn] = struct{}{}
+		s.mutex.Unlock()
+
+		s.active.Go(func() {
+			defer s.forget(conn)
+			s.handle(conn)
+		})
+	}
+}
+
+// Stop accepting players and give the ones connected some time to get their redirect
+func (s *Server) Shutdown(timeout time.Duration) {
+	s.mutex.Lock()
+	select {
+	case <-s.closing:
+	default:
+		close(s.closing)
+	}
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	s.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		s.mutex.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mutex.Unlock()
+		<-done
+	}
+}
+
+// Helper function for closing a connection that has been handled
+func (s *Server) forget(conn net.Conn) {
+	conn.Close()
+
+	s.mutex.Lock()
+	delete(s.conns, conn)
+	s.mutex.Unlock()
+}
+
+// Helper function for sending a single player to a match
+func (s *Server) handle(conn net.Conn) {
+	conn.SetReadDeadline(time.Now().Add(s.config.HandshakeTimeout))
+	handshake, err := s.protocol.ReadHandshake(bufio.NewReader(conn))
+	if err != nil {
+		if errors.Is(err, ErrInvalidHandshake) {
+			s.protocol.Reject(conn, ReasonInvalidHandshake)
+		}
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	// Anyone could claim to be any account, so it has to be proven before the player is queued
+	if !s.authenticator.Authenticate(handshake) {
+		s.protocol.Reject(conn, ReasonNotAuthenticated)
+		return
+	}
+
+	status, ok := service.QueuePlayer(s.config.Game, handshake.Account)
+	if !ok {
+		s.protocol.Reject(conn, ReasonAlreadyPlaying)
+		return
+	}
+
+	// Wait for a slot (the player is taken out of the queue again in case none shows up)
+	deadline := time.After(s.config.QueueTimeout)
+	ticker := time.NewTicker(QueuePollInterval)
+	defer ticker.Stop()
+	for !status.Assigned {
+		if err := s.protocol.Wait(conn, status.Position); err != nil {
+			service.CancelQueue(handshake.Account)
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			service.CancelQueue(handshake.Account)
+			s.protocol.Reject(conn, ReasonNoMatch)
+			return
+		case <-s.closing:
+			service.CancelQueue(handshake.Account)
+			s.protocol.Reject(conn, ReasonShuttingDown)
+			return
+		}
+
+		status, ok = service.GetQueueStatus(handshake.Account)
+		if !ok {
+			s.protocol.Reject(conn, ReasonNoMatch)
+			return
+		}
+	}
+
+	address, port, ok := service.GetServerDetails(status.Server)
+	if !ok {
+		service.CancelQueue(handshake.Account)
+		s.protocol.Reject(conn, ReasonNoMatch)
+		return
+	}
+	ticket, _ := service.IssueJoinTicket(handshake.Account)
+
+	if err := s.protocol.Redirect(conn, Destination{
+		Address: address,
+		Port:    port,
+		Token:   status.Tokens[handshake.Account],
+		Ticket:  ticket,
+	}); err != nil {
+		log.Println("Couldn't redirect", handshake.Account, ":", err)
+	}
+}
@@ -12,15 +12,8 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-const (
-	DeviceAuthURL   = "https://oauth.accounts.hytale.com/oauth2/device/auth"
-	TokenURL        = "https://oauth.accounts.hytale.com/oauth2/token"
-	ProfilesURL     = "https://account-data.hytale.com/my-account/get-profiles"
-	GameSessionURL  = "https://sessions.hytale.com/game-session/new"
-	ClientID        = "hytale-server"
-	Scope           = "openid offline auth:server"
-	DefaultInterval = 5 * time.Second
-)
+// The endpoints and client details are in service/oauth.go (they are also needed for refreshing tokens)
+const DefaultInterval = 5 * time.Second
 
 type DeviceCodeResponse struct {
 	DeviceCode              string `json:"device_code"`
@@ -31,15 +24,6 @@ type DeviceCodeResponse struct {
 	Interval                int    `json:"interval"`
 }
 
-type TokenResponse struct {
-	AccessToken  string `json:"access_token"`
-	TokenType    string `json:"token_type"`
-	ExpiresIn    int    `json:"expires_in"`
-	RefreshToken string `json:"refresh_token"`
-	Scope        string `json:"scope"`
-	Error        string `json:"error,omitempty"`
-}
-
 type ProfilesResponse struct {
 	Owner    string    `json:"owner"`
 	Profiles []Profile `json:"profiles"`
@@ -74,10 +58,10 @@ func requestDeviceCode() (*DeviceCodeResponse, error) {
 
 	// OAuth endpoints require application/x-www-form-urlencoded
 	data := url.Values{}
-	data.Set("client_id", ClientID)
-	data.Set("scope", Scope)
+	data.Set("client_id", service.ClientID)
+	data.Set("scope", service.Scope)
 
-	deviceCode, err := util.PostForm[DeviceCodeResponse](DeviceAuthURL, data, util.Headers{})
+	deviceCode, err := util.PostForm[DeviceCodeResponse](service.DeviceAuthURL, data, util.Headers{})
 	if err != nil {
 		return nil, err
 	}
@@ -116,7 +100,7 @@ func pollForToken(deviceCode *DeviceCodeResponse) {
 
 		if tokenResp.AccessToken != "" {
 			log.Printf("✓ Access token obtained successfully!")
-			handleTokenSuccess(tokenResp.AccessToken, tokenResp.RefreshToken)
+			handleTokenSuccess(tokenResp)
 			return
 		}
 	}
@@ -124,14 +108,14 @@ func pollForToken(deviceCode *DeviceCodeResponse) {
 	log.Printf("Device code expired without authorization")
 }
 
-func pollTokenEndpoint(deviceCode string) (*TokenResponse, error) {
+func pollTokenEndpoint(deviceCode string) (*service.TokenResponse, error) {
 	// OAuth endpoints require application/x-www-form-urlencoded
 	data := url.Values{}
-	data.Set("client_id", ClientID)
+	data.Set("client_id", service.ClientID)
 	data.Set("grant_type", "urn:ietf:params:oauth:grant-type:device_code")
 	data.Set("device_code", deviceCode)
 
-	tokenResp, err := util.PostFormAllowErrors[TokenResponse](TokenURL, data, util.Headers{})
+	tokenResp, err := util.PostFormAllowErrors[service.TokenResponse](service.GetTokenURL(), data, util.Headers{})
 	if err != nil {
 		return nil, err
 	}
@@ -139,9 +123,9 @@ func pollTokenEndpoint(deviceCode string) (*TokenResponse, error) {
 	return &tokenResp, nil
 }
 
-func handleTokenSuccess(accessToken, refreshToken string) {
+func handleTokenSuccess(tokenResp *service.TokenResponse) {
 	// Step 4: Get Available Profiles
-	profiles, err := getProfiles(accessToken)
+	profiles, err := getProfiles(tokenResp.AccessToken)
 	if err != nil {
 		log.Printf("Error getting profiles: %v", err)
 		return
@@ -158,8 +142,9 @@ func handleTokenSuccess(accessToken, refreshToken string) {
 
 	// Store the token
 	token := service.Token{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		AccessToken:  tokenResp.AccessToken,
+		RefreshToken: tokenResp.RefreshToken,
+		ExpiresAt:    tokenResp.Expiry(),
 		Account:      profile.Username,
 		UUID:         profiles.Owner,
 	}
@@ -173,7 +158,7 @@ func getProfiles(accessToken string) (*ProfilesResponse, error) {
 		"Authorization": fmt.Sprintf("Bearer %s", accessToken),
 	}
 
-	profiles, err := util.Get[ProfilesResponse](ProfilesURL, headers)
+	profiles, err := util.Get[ProfilesResponse](service.ProfilesURL, headers)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,199 @@
+package control_routes_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	control_routes "github.com/Liphium/hytale-matchmaking/routes/control"
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/Liphium/hytale-matchmaking/util"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"resty.dev/v3"
+)
+
+func TestAdmin(t *testing.T) {
+	service.ResetAll()
+	t.Setenv("TOKEN_FILE_LOCATION", t.TempDir())
+
+	const game = "battle"
+
+	id := service.AddToken(service.Token{
+		AccessToken:  "secret-access",
+		RefreshToken: "secret-refresh",
+		Account:      "owner",
+		ExpiresAt:    time.Now().Add(time.Hour),
+	})
+	_, ok := service.GetFreeToken()
+	assert.True(t, ok)
+	service.AddToken(service.Token{
+		AccessToken: "other-access",
+		Account:     "other",
+	})
+
+	assert.True(t, service.CreateServer(id, "localhost", 3000))
+	assert.True(t, service.AddMatch(id, service.MatchCreate{
+		ID:   1,
+		Game: game,
+	}, []string{"a", "b"}))
+	assert.True(t, service.AddMatch(id, service.MatchCreate{
+		ID:   2,
+		Game: "other",
+	}, []string{"c"}))
+	service.SetMatchState(id, 1, service.MatchStateAccepting)
+
+	token, _, ok := service.CreatePlayerIfPossible(game, "player")
+	assert.True(t, ok)
+	_, ok = service.ConfirmPlayerToken(id, "player", token)
+	assert.True(t, ok)
+
+	get := func(t *testing.T, path string, v any) int {
+		client := resty.New()
+		defer client.Close()
+
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			Get(util.DefaultPath(path))
+		assert.Nil(t, err)
+		if res.StatusCode() == fiber.StatusOK && v != nil {
+			testing_util.Unmarshal(t, res.Bytes(), v)
+		}
+		return res.StatusCode()
+	}
+	post := func(t *testing.T, path string, body any) int {
+		client := resty.New()
+		defer client.Close()
+
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			SetBody(body).
+			Post(util.DefaultPath(path))
+		assert.Nil(t, err)
+		return res.StatusCode()
+	}
+
+	t.Run("credential is required", func(t *testing.T) {
+		client := resty.New()
+		defer client.Close()
+
+		res, err := client.R().Get(util.DefaultPath("/api/control/servers"))
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, res.StatusCode())
+	})
+
+	t.Run("lobbies can't use the admin API", func(t *testing.T) {
+		t.Setenv("LOBBY_CREDENTIAL", "lobby")
+		client := resty.New()
+		defer client.Close()
+
+		res, err := client.R().
+			SetHeader("Credential", "lobby").
+			Get(util.DefaultPath("/api/control/servers"))
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusForbidden, res.StatusCode())
+	})
+
+	t.Run("list and get servers", func(t *testing.T) {
+		var servers []service.ServerView
+		assert.Equal(t, fiber.StatusOK, get(t, "/api/control/servers", &servers))
+		assert.Len(t, servers, 1)
+		assert.Len(t, servers[0].Matches, 2)
+
+		var server service.ServerView
+		assert.Equal(t, fiber.StatusOK, get(t, "/api/control/servers/"+strconv.Itoa(id), &server))
+		assert.Equal(t, "localhost", server.IP)
+		assert.Len(t, server.Players, 1)
+		assert.True(t, server.Players[0].Confirmed)
+		assert.Greater(t, server.TTLLeft, 0.0)
+
+		assert.Equal(t, fiber.StatusNotFound, get(t, "/api/control/servers/999", nil))
+	})
+
+	t.Run("list matches by game and state", func(t *testing.T) {
+		var matches []service.MatchView
+		get(t, "/api/control/matches", &matches)
+		assert.Len(t, matches, 2)
+
+		get(t, "/api/control/matches?game="+game+"&state="+service.MatchStateAccepting, &matches)
+		assert.Len(t, matches, 1)
+		assert.Equal(t, []string{"player"}, matches[0].Players)
+		assert.Equal(t, 1, matches[0].FreeTokens)
+
+		get(t, "/api/control/matches?state="+service.MatchStateFull, &matches)
+		assert.Empty(t, matches)
+	})
+
+	t.Run("tokens don't contain secrets", func(t *testing.T) {
+		client := resty.New()
+		defer client.Close()
+
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			Get(util.DefaultPath("/api/control/tokens"))
+		assert.Nil(t, err)
+		assert.False(t, strings.Contains(res.String(), "secret"))
+		assert.False(t, strings.Contains(res.String(), "other-access"))
+
+		var tokens []service.TokenView
+		testing_util.Unmarshal(t, res.Bytes(), &tokens)
+		assert.Len(t, tokens, 2)
+		assert.True(t, tokens[0].Used)
+		assert.True(t, tokens[0].CanRefresh)
+		assert.False(t, tokens[1].Used)
+	})
+
+	t.Run("look up and kick a player", func(t *testing.T) {
+		var lookup service.PlayerLookup
+		assert.Equal(t, fiber.StatusOK, get(t, "/api/control/players/player", &lookup))
+		assert.NotNil(t, lookup.Player)
+		assert.Equal(t, 1, lookup.Player.Match)
+
+		assert.Equal(t, fiber.StatusOK, post(t, "/api/control/kick_player", control_routes.KickPlayerRequest{Account: "player"}))
+		assert.False(t, service.IsOnServerOrWaiting("player"))
+		assert.Equal(t, fiber.StatusNotFound, get(t, "/api/control/players/player", nil))
+		assert.Equal(t, fiber.StatusNotFound, post(t, "/api/control/kick_player", control_routes.KickPlayerRequest{Account: "player"}))
+	})
+
+	t.Run("look up a queued player", func(t *testing.T) {
+		_, ok := service.QueuePlayer("queued-game", "waiting")
+		assert.True(t, ok)
+
+		var lookup service.PlayerLookup
+		assert.Equal(t, fiber.StatusOK, get(t, "/api/control/players/waiting", &lookup))
+		assert.Nil(t, lookup.Player)
+		assert.Equal(t, "queued-game", lookup.Queue.Game)
+		assert.Equal(t, 1, lookup.Queue.Position)
+	})
+
+	t.Run("end a match", func(t *testing.T) {
+		events, unsubscribe := service.SubscribeToEvents(id)
+		defer unsubscribe()
+
+		token, _, ok := service.CreatePlayerIfPossible(game, "player")
+		assert.True(t, ok)
+		_, ok = service.ConfirmPlayerToken(id, "player", token)
+		assert.True(t, ok)
+
+		assert.Equal(t, fiber.StatusOK, post(t, "/api/control/end_match", control_routes.EndMatchRequest{Server: id, Match: 1}))
+		event := testing_util.WaitForEvent(t, events, service.EventMatchEnded)
+		assert.Equal(t, 1, event.Data.(service.MatchEvent).Match)
+
+		_, ok = service.GetMatchFromServer(id, 1)
+		assert.False(t, ok)
+		assert.Eventually(t, func() bool { return !service.IsOnServerOrWaiting("player") }, time.Second, 10*time.Millisecond)
+		assert.Equal(t, fiber.StatusNotFound, post(t, "/api/control/end_match", control_routes.EndMatchRequest{Server: id, Match: 1}))
+	})
+
+	t.Run("evict a server", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusOK, post(t, "/api/control/evict_server", control_routes.EvictServerRequest{Server: id}))
+		assert.Equal(t, fiber.StatusNotFound, get(t, "/api/control/servers/"+strconv.Itoa(id), nil))
+		assert.Eventually(t, func() bool {
+			free, _ := service.TokenPoolStats()
+			return free == 2
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, fiber.StatusNotFound, post(t, "/api/control/evict_server", control_routes.EvictServerRequest{Server: id}))
+	})
+}
@@ -1,22 +1,28 @@
 package control_routes
 
 import (
-	"github.com/Liphium/hytale-matchmaking/util"
+	"github.com/Liphium/hytale-matchmaking/service"
 	"github.com/gofiber/fiber/v2"
 )
 
 func SetupRoutes(router fiber.Router) {
 
-	// Require the credential as a query parameter
-	router.Use(func(c *fiber.Ctx) error {
+	// Read-only dashboard showing the admin API in the browser (it sends the credential itself)
+	router.Get("/dashboard", dashboard)
 
-		cred := c.Query("credential", "-")
-		if cred != util.GetCredential() {
-			return c.SendStatus(fiber.StatusUnauthorized)
-		}
-
-		return c.Next()
-	})
+	// Everything else is only for admins
+	router.Use(service.AuthMiddleware(service.RoleAdmin))
 
 	router.Get("/add_new", addNewToken)
+
+	// Admin API for inspecting and managing everything that's currently going on
+	router.Get("/servers", listServers)
+	router.Get("/servers/:id", getServer)
+	router.Get("/matches", listMatches)
+	router.Get("/players/:account", lookupPlayer)
+	router.Get("/tokens", listTokens)
+	router.Get("/queues", listQueues)
+	router.Post("/end_match", endMatch)
+	router.Post("/kick_player", kickPlayer)
+	router.Post("/evict_server", evictServer)
 }
@@ -0,0 +1,18 @@
+package control_routes
+
+import (
+	_ "embed"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Everything (styles and scripts included) is in one file, the page asks for the credential itself and sends it as a header
+//
+//go:embed dashboard.html
+var dashboardPage []byte
+
+// Route: GET /api/control/dashboard (the page has nothing secret in it, so it doesn't need the credential)
+func dashboard(c *fiber.Ctx) error {
+	c.Type("html", "utf-8")
+	return c.Send(dashboardPage)
+}
@@ -0,0 +1,52 @@
+package control_routes_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/Liphium/hytale-matchmaking/util"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"resty.dev/v3"
+)
+
+func TestDashboard(t *testing.T) {
+	service.ResetAll()
+
+	client := resty.New()
+	defer client.Close()
+
+	t.Run("page is served without the credential", func(t *testing.T) {
+		res, err := client.R().Get(util.DefaultPath("/api/control/dashboard"))
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+		assert.True(t, strings.HasPrefix(res.Header().Get("Content-Type"), "text/html"))
+		assert.Contains(t, res.String(), "Matchmaking dashboard")
+	})
+
+	t.Run("credential isn't accepted in the URL", func(t *testing.T) {
+		res, err := client.R().
+			SetQueryParam("credential", util.GetCredential()).
+			Get(util.DefaultPath("/api/control/queues"))
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, res.StatusCode())
+	})
+
+	t.Run("queues are summarized per game", func(t *testing.T) {
+		_, ok := service.QueuePlayer("battle", "solo")
+		assert.True(t, ok)
+		_, ok = service.QueueParty("battle", []string{"a", "b"})
+		assert.True(t, ok)
+
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			Get(util.DefaultPath("/api/control/queues"))
+		assert.Nil(t, err)
+
+		var queues []service.QueueSummary
+		testing_util.Unmarshal(t, res.Bytes(), &queues)
+		assert.Equal(t, []service.QueueSummary{{Game: "battle", Entries: 2, Players: 3}}, queues)
+	})
+}
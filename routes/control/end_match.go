@@ -0,0 +1,24 @@
+package control_routes
+
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type EndMatchRequest struct {
+	Server int `json:"server"`
+	Match  int `json:"match"`
+}
+
+// Route: POST /api/control/end_match
+func endMatch(c *fiber.Ctx) error {
+	var req EndMatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	if !service.EndMatch(req.Server, req.Match) {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
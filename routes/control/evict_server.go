@@ -0,0 +1,23 @@
+package control_routes
+
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type EvictServerRequest struct {
+	Server int `json:"server"`
+}
+
+// Route: POST /api/control/evict_server
+func evictServer(c *fiber.Ctx) error {
+	var req EvictServerRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	if !service.EvictServer(req.Server) {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
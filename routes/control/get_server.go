@@ -0,0 +1,20 @@
+package control_routes
+
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Route: GET /api/control/servers/:id
+func getServer(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	server, ok := service.GetServerView(id)
+	if !ok {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	return c.JSON(server)
+}
@@ -0,0 +1,23 @@
+package control_routes
+
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type KickPlayerRequest struct {
+	Account string `json:"account"`
+}
+
+// Route: POST /api/control/kick_player
+func kickPlayer(c *fiber.Ctx) error {
+	var req KickPlayerRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	if !service.KickPlayer(req.Account) {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,11 @@
+package control_routes
+
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Route: GET /api/control/matches?game=&state= (both filters are optional)
+func listMatches(c *fiber.Ctx) error {
+	return c.JSON(service.ListMatches(c.Query("game"), c.Query("state")))
+}
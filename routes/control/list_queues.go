@@ -0,0 +1,11 @@
+package control_routes
+
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Route: GET /api/control/queues
+func listQueues(c *fiber.Ctx) error {
+	return c.JSON(service.ListQueues())
+}
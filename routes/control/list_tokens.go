@@ -0,0 +1,11 @@
+package control_routes
+
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Route: GET /api/control/tokens
+func listTokens(c *fiber.Ctx) error {
+	return c.JSON(service.ListTokens())
+}
@@ -0,0 +1,15 @@
+package control_routes
+
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Route: GET /api/control/players/:account
+func lookupPlayer(c *fiber.Ctx) error {
+	player, ok := service.LookupPlayer(c.Params("account"))
+	if !ok {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	return c.JSON(player)
+}
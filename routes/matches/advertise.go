@@ -8,7 +8,13 @@ import (
 type AdvertiseMatchRequest struct {
 	Server int                 `json:"server"`
 	Match  service.MatchCreate `json:"match"`
-	Tokens []string            `json:"tokens"` // Tokens for all the players (required to let the match server check tokens without web requests)
+	Tokens []string            `json:"tokens"` // Tokens for all the players (can be left out with max players set when the server verifies join tickets instead)
+
+	SpectatorTokens []string `json:"spectator_tokens,omitempty"` // Tokens for spectators (the match can't be spectated without them)
+}
+
+type AdvertiseMatchResponse struct {
+	JoinCode string `json:"join_code,omitempty"` // Only set for private matches, players use it to join
 }
 
 // Route: POST /api/matches/advertise
@@ -18,9 +24,17 @@ func AdvertiseMatch(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusBadRequest)
 	}
 
+	if !service.OwnsServer(c, req.Server) {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	req.Match.SpectatorTokens = req.SpectatorTokens
 	if !service.AddMatch(req.Server, req.Match, req.Tokens) {
 		return c.SendStatus(fiber.StatusBadRequest)
 	}
 
-	return c.SendStatus(fiber.StatusOK)
+	code, _ := service.GetJoinCode(req.Server, req.Match.ID)
+	return c.JSON(AdvertiseMatchResponse{
+		JoinCode: code,
+	})
 }
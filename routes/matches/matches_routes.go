@@ -7,9 +7,11 @@ import (
 
 func SetupRoutes(router fiber.Router) {
 
-	// Require the credential as a header
-	router.Use(service.DefaultAuthMiddleware())
+	// Require the secret of the server as a header (every route checks that it's actually for that server)
+	router.Use(service.AuthMiddleware(service.RoleGameServer))
 
 	router.Post("/advertise", AdvertiseMatch)
 	router.Post("/set_state", SetMatchState)
+	router.Post("/report", ReportMatch)
+	router.Post("/status", MatchStatus)
 }
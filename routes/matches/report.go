@@ -0,0 +1,37 @@
+package matches_routes
+
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReportMatchRequest struct {
+	Server     int        `json:"server"`
+	Match      int        `json:"match"`
+	Placements [][]string `json:"placements"` // Starting with the winners (players in the same placement tied, e.g. a team)
+}
+
+type ReportMatchResponse struct {
+	Ratings map[string]float64 `json:"ratings"` // Account -> new rating
+}
+
+// Route: POST /api/matches/report
+func ReportMatch(c *fiber.Ctx) error {
+	var req ReportMatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	if !service.OwnsServer(c, req.Server) {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	ratings, ok := service.ReportMatchResult(req.Server, req.Match, req.Placements)
+	if !ok {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	return c.JSON(ReportMatchResponse{
+		Ratings: ratings,
+	})
+}
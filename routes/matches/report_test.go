@@ -0,0 +1,148 @@
+package matches_routes_test
+
+import (
+	"testing"
+	"time"
+
+	matches_routes "github.com/Liphium/hytale-matchmaking/routes/matches"
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/Liphium/hytale-matchmaking/util"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"resty.dev/v3"
+)
+
+func TestReport(t *testing.T) {
+	service.ResetAll()
+	t.Setenv("TOKEN_FILE_LOCATION", t.TempDir())
+
+	const (
+		id   = 1
+		game = "battle"
+	)
+
+	assert.True(t, service.CreateServer(id, "localhost", 3000))
+
+	// Create a match the winner and loser are playing in (the previous one ends)
+	play := func(t *testing.T, match int) {
+		if match > 1 {
+			assert.True(t, service.SetMatchState(id, match-1, service.MatchStateEnd))
+			assert.Eventually(t, func() bool {
+				return !service.IsOnServerOrWaiting("winner") && !service.IsOnServerOrWaiting("loser")
+			}, time.Second, 10*time.Millisecond)
+		}
+		assert.True(t, service.AddMatch(id, service.MatchCreate{
+			ID:   match,
+			Game: game,
+		}, []string{"a", "b"}))
+		assert.True(t, service.SetMatchState(id, match, service.MatchStateAccepting))
+		tokens, _, ok := service.CreatePartyIfPossible(game, []string{"winner", "loser"})
+		assert.True(t, ok)
+		for i, account := range []string{"winner", "loser"} {
+			_, ok := service.ConfirmToken(id, account, tokens[i])
+			assert.True(t, ok)
+		}
+	}
+	play(t, 1)
+
+	report := func(t *testing.T, req matches_routes.ReportMatchRequest) (int, matches_routes.ReportMatchResponse) {
+		client := resty.New()
+		defer client.Close()
+
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			SetBody(req).
+			Post(util.DefaultPath("/api/matches/report"))
+		assert.Nil(t, err)
+
+		var r matches_routes.ReportMatchResponse
+		if res.StatusCode() == fiber.StatusOK {
+			testing_util.Unmarshal(t, res.Bytes(), &r)
+		}
+		return res.StatusCode(), r
+	}
+
+	t.Run("winners gain what losers lose", func(t *testing.T) {
+		status, r := report(t, matches_routes.ReportMatchRequest{
+			Server:     id,
+			Match:      1,
+			Placements: [][]string{{"winner"}, {"loser"}},
+		})
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.InDelta(t, service.DefaultRating+16, r.Ratings["winner"], 0.001)
+		assert.InDelta(t, service.DefaultRating-16, r.Ratings["loser"], 0.001)
+		assert.InDelta(t, r.Ratings["winner"], service.GetRating(game, "winner"), 0.001)
+	})
+
+	t.Run("a match can only be reported once", func(t *testing.T) {
+		status, _ := report(t, matches_routes.ReportMatchRequest{
+			Server:     id,
+			Match:      1,
+			Placements: [][]string{{"winner"}, {"loser"}},
+		})
+		assert.Equal(t, fiber.StatusBadRequest, status)
+	})
+
+	t.Run("beating a weaker player gives less", func(t *testing.T) {
+		play(t, 2)
+		before := service.GetRating(game, "winner")
+		_, r := report(t, matches_routes.ReportMatchRequest{
+			Server:     id,
+			Match:      2,
+			Placements: [][]string{{"winner"}, {"loser"}},
+		})
+		assert.Greater(t, r.Ratings["winner"], before)
+		assert.Less(t, r.Ratings["winner"]-before, 16.0)
+	})
+
+	t.Run("a single placement can't be reported", func(t *testing.T) {
+		play(t, 3)
+		status, _ := report(t, matches_routes.ReportMatchRequest{
+			Server:     id,
+			Match:      3,
+			Placements: [][]string{{"winner", "loser"}},
+		})
+		assert.Equal(t, fiber.StatusBadRequest, status)
+	})
+
+	t.Run("only players of the match can be placed (once)", func(t *testing.T) {
+		for _, placements := range [][][]string{
+			{{"winner"}, {"loser"}, {"stranger"}},
+			{{"winner"}, {"loser", "winner"}},
+		} {
+			status, _ := report(t, matches_routes.ReportMatchRequest{
+				Server:     id,
+				Match:      3,
+				Placements: placements,
+			})
+			assert.Equal(t, fiber.StatusBadRequest, status)
+		}
+		assert.Equal(t, service.DefaultRating, service.GetRating(game, "stranger"))
+	})
+
+	t.Run("players that quit can still be placed", func(t *testing.T) {
+		play(t, 4)
+		assert.True(t, service.LeavePlayer(id, service.PlayerLeave{
+			Player: "loser",
+			Reason: service.LeaveReasonQuit,
+		}))
+		before := service.GetRating(game, "loser")
+		status, r := report(t, matches_routes.ReportMatchRequest{
+			Server:     id,
+			Match:      4,
+			Placements: [][]string{{"winner"}, {"loser"}},
+		})
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Less(t, r.Ratings["loser"], before)
+	})
+
+	t.Run("unknown match can't be reported", func(t *testing.T) {
+		status, _ := report(t, matches_routes.ReportMatchRequest{
+			Server:     id,
+			Match:      2,
+			Placements: [][]string{{"winner"}, {"loser"}},
+		})
+		assert.Equal(t, fiber.StatusBadRequest, status)
+	})
+}
@@ -18,8 +18,15 @@ func SetMatchState(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusBadRequest)
 	}
 
-	if !service.SetMatchState(req.Server, req.Match, req.State) {
-		return c.SendStatus(fiber.StatusBadRequest)
+	if !service.OwnsServer(c, req.Server) {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	// Tell the server what's wrong, a typo in the state would otherwise be hard to notice
+	if err := service.TransitionMatch(req.Server, req.Match, req.State); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
 
 	return c.SendStatus(fiber.StatusOK)
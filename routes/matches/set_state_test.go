@@ -49,6 +49,29 @@ func TestSetState(t *testing.T) {
 		assert.Equal(t, service.MatchStateFull, match.State)
 	})
 
+	t.Run("invalid states are rejected with a reason", func(t *testing.T) {
+		client := resty.New()
+		defer client.Close()
+
+		for _, state := range []string{"acepting", service.MatchStateAvailable} {
+			res, err := client.R().
+				SetHeaders(util.CredentialHeaders()).
+				SetBody(matches_routes.MatchSetStateRequest{
+					Server: id,
+					Match:  created.ID,
+					State:  state,
+				}).
+				Post(util.DefaultPath("/api/matches/set_state"))
+			assert.Nil(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, res.StatusCode())
+			assert.Contains(t, res.String(), "error")
+		}
+
+		match, ok := service.GetMatchFromServer(id, created.ID)
+		assert.True(t, ok)
+		assert.Equal(t, service.MatchStateFull, match.State)
+	})
+
 	t.Run("end deletes match", func(t *testing.T) {
 		client := resty.New()
 		defer client.Close()
@@ -0,0 +1,57 @@
+package matches_routes
+
+import (
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type MatchStatusRequest struct {
+	Server int `json:"server"`
+	Match  int `json:"match"`
+}
+
+type MatchStatusResponse struct {
+	Players      int        `json:"players"`   // Players with a slot in the match (including the ones that didn't join yet)
+	Confirmed    int        `json:"confirmed"` // Players that actually joined
+	MinPlayers   int        `json:"min_players"`
+	MaxPlayers   int        `json:"max_players"`
+	Fill         float64    `json:"fill"` // Share of the slots that are taken (0 to 1)
+	Ready        bool       `json:"ready"`
+	ReadySince   *time.Time `json:"ready_since,omitempty"`
+	ForceStartAt *time.Time `json:"force_start_at,omitempty"` // When the server will be told to start the match
+}
+
+// Route: POST /api/matches/status
+func MatchStatus(c *fiber.Ctx) error {
+	var req MatchStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	if !service.OwnsServer(c, req.Server) {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	readiness, ok := service.GetMatchReadiness(req.Server, req.Match)
+	if !ok {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	res := MatchStatusResponse{
+		Players:    readiness.Players,
+		Confirmed:  readiness.Confirmed,
+		MinPlayers: readiness.MinPlayers,
+		MaxPlayers: readiness.MaxPlayers,
+		Fill:       readiness.Fill,
+		Ready:      readiness.Ready,
+	}
+	if readiness.Ready {
+		res.ReadySince = &readiness.ReadySince
+	}
+	if !readiness.ForceStartAt.IsZero() {
+		res.ForceStartAt = &readiness.ForceStartAt
+	}
+	return c.JSON(res)
+}
@@ -0,0 +1,78 @@
+package matches_routes_test
+
+import (
+	"testing"
+
+	matches_routes "github.com/Liphium/hytale-matchmaking/routes/matches"
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/Liphium/hytale-matchmaking/util"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"resty.dev/v3"
+)
+
+func TestMatchStatus(t *testing.T) {
+	service.ResetAll()
+
+	const (
+		id   = 1
+		game = "battle"
+	)
+
+	assert.True(t, service.CreateServer(id, "localhost", 3000))
+	assert.True(t, service.AddMatch(id, service.MatchCreate{
+		ID:         1,
+		Game:       game,
+		MinPlayers: 1,
+	}, []string{"a", "b"}))
+	service.SetMatchState(id, 1, service.MatchStateAccepting)
+
+	status := func(t *testing.T, match int) (int, matches_routes.MatchStatusResponse) {
+		client := resty.New()
+		defer client.Close()
+
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			SetBody(matches_routes.MatchStatusRequest{
+				Server: id,
+				Match:  match,
+			}).
+			Post(util.DefaultPath("/api/matches/status"))
+		assert.Nil(t, err)
+
+		var r matches_routes.MatchStatusResponse
+		if res.StatusCode() == fiber.StatusOK {
+			testing_util.Unmarshal(t, res.Bytes(), &r)
+		}
+		return res.StatusCode(), r
+	}
+
+	t.Run("empty match isn't ready", func(t *testing.T) {
+		code, r := status(t, 1)
+		assert.Equal(t, fiber.StatusOK, code)
+		assert.False(t, r.Ready)
+		assert.Nil(t, r.ReadySince)
+		assert.Equal(t, 2, r.MaxPlayers)
+		assert.Equal(t, 0.0, r.Fill)
+	})
+
+	t.Run("ready once a player joined", func(t *testing.T) {
+		token, server, ok := service.CreatePlayerIfPossible(game, "player")
+		assert.True(t, ok)
+		_, ok = service.ConfirmPlayerToken(server, "player", token)
+		assert.True(t, ok)
+
+		_, r := status(t, 1)
+		assert.True(t, r.Ready)
+		assert.NotNil(t, r.ReadySince)
+		assert.Nil(t, r.ForceStartAt)
+		assert.Equal(t, 1, r.Confirmed)
+		assert.Equal(t, 0.5, r.Fill)
+	})
+
+	t.Run("unknown match", func(t *testing.T) {
+		code, _ := status(t, 2)
+		assert.Equal(t, fiber.StatusNotFound, code)
+	})
+}
@@ -12,7 +12,8 @@ type ConfirmPlayerRequest struct {
 }
 
 type ConfirmPlayerResponse struct {
-	Match int `json:"match"`
+	Match     int  `json:"match"`
+	Spectator bool `json:"spectator"` // Whether the player joined as a spectator
 }
 
 // Route: POST /api/players/confirm
@@ -22,12 +23,17 @@ func ConfirmPlayer(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusBadRequest)
 	}
 
+	if !service.OwnsServer(c, req.Server) {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
 	// Confirm the player token and return the match when it worked
-	match, ok := service.ConfirmPlayerToken(req.Server, req.Player, req.Token)
+	confirmation, ok := service.ConfirmToken(req.Server, req.Player, req.Token)
 	if !ok {
 		return c.SendStatus(fiber.StatusBadRequest)
 	}
 	return c.JSON(ConfirmPlayerResponse{
-		Match: match,
+		Match:     confirmation.Match,
+		Spectator: confirmation.Spectator,
 	})
 }
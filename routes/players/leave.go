@@ -0,0 +1,35 @@
+package players_routes
+
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type LeavePlayerRequest struct {
+	Server int    `json:"server"`
+	Player string `json:"player"`
+	Reason string `json:"reason"`           // quit, disconnected, kicked or match_ended
+	Retire bool   `json:"retire,omitempty"` // Don't give the player's token back to the match
+}
+
+// Route: POST /api/players/leave
+func LeavePlayer(c *fiber.Ctx) error {
+	var req LeavePlayerRequest
+	if err := c.BodyParser(&req); err != nil || !service.IsValidLeaveReason(req.Reason) {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	if !service.OwnsServer(c, req.Server) {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	if !service.LeavePlayer(req.Server, service.PlayerLeave{
+		Player: req.Player,
+		Reason: req.Reason,
+		Retire: req.Retire,
+	}) {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,38 @@
+package players_routes
+
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type LeaveBulkRequest struct {
+	Server  int                   `json:"server"`
+	Players []service.PlayerLeave `json:"players"`
+}
+
+type LeaveBulkResponse struct {
+	Unknown []string `json:"unknown"` // Players that weren't on the server (already gone or never there)
+}
+
+// Route: POST /api/players/leave_bulk
+func LeaveBulk(c *fiber.Ctx) error {
+	var req LeaveBulkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	if !service.OwnsServer(c, req.Server) {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	// Nothing is removed in case any of the reasons are wrong
+	for _, leave := range req.Players {
+		if !service.IsValidLeaveReason(leave.Reason) {
+			return c.SendStatus(fiber.StatusBadRequest)
+		}
+	}
+
+	return c.JSON(LeaveBulkResponse{
+		Unknown: service.LeavePlayers(req.Server, req.Players),
+	})
+}
@@ -0,0 +1,124 @@
+package players_routes_test
+
+import (
+	"testing"
+
+	players_routes "github.com/Liphium/hytale-matchmaking/routes/players"
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/Liphium/hytale-matchmaking/util"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"resty.dev/v3"
+)
+
+func TestLeavePlayer(t *testing.T) {
+	service.ResetAll()
+
+	// A server with a match that has a slot for everyone
+	const (
+		serverId = 1
+		game     = "battle"
+		matchId  = 1
+	)
+	assert.True(t, service.CreateServer(serverId, "localhost", 3000))
+	assert.True(t, service.CreateServer(2, "localhost", 3001))
+	assert.True(t, service.AddMatch(serverId, service.MatchCreate{
+		ID:   matchId,
+		Game: game,
+	}, []string{"a", "b", "c", "d"}))
+	assert.True(t, service.SetMatchState(serverId, matchId, service.MatchStateAccepting))
+
+	// Give everyone a slot and let them join
+	for _, player := range []string{"p1", "p2", "p3", "p4"} {
+		token, _, ok := service.CreatePlayerIfPossible(game, player)
+		assert.True(t, ok)
+		_, ok = service.ConfirmPlayerToken(serverId, player, token)
+		assert.True(t, ok)
+	}
+
+	client := resty.New()
+	defer client.Close()
+	post := func(t *testing.T, path string, body any) *resty.Response {
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			SetBody(body).
+			Post(util.DefaultPath(path))
+		assert.Nil(t, err)
+		return res
+	}
+	freeTokens := func(t *testing.T) int {
+		match, ok := service.GetMatchFromServer(serverId, matchId)
+		assert.True(t, ok)
+		match.Mutex.RLock()
+		defer match.Mutex.RUnlock()
+		return len(match.TokenStore)
+	}
+
+	t.Run("leaving gives the token back", func(t *testing.T) {
+		res := post(t, "/api/players/leave", players_routes.LeavePlayerRequest{
+			Server: serverId,
+			Player: "p1",
+			Reason: service.LeaveReasonQuit,
+		})
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+		assert.False(t, service.IsOnServerOrWaiting("p1"))
+		assert.Equal(t, 1, freeTokens(t))
+	})
+
+	t.Run("retired tokens aren't given back", func(t *testing.T) {
+		res := post(t, "/api/players/leave", players_routes.LeavePlayerRequest{
+			Server: serverId,
+			Player: "p2",
+			Reason: service.LeaveReasonDisconnected,
+			Retire: true,
+		})
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+		assert.False(t, service.IsOnServerOrWaiting("p2"))
+		assert.Equal(t, 1, freeTokens(t))
+	})
+
+	t.Run("unknown reasons and players are rejected", func(t *testing.T) {
+		res := post(t, "/api/players/leave", players_routes.LeavePlayerRequest{
+			Server: serverId,
+			Player: "p3",
+			Reason: "bored",
+		})
+		assert.Equal(t, fiber.StatusBadRequest, res.StatusCode())
+
+		res = post(t, "/api/players/leave", players_routes.LeavePlayerRequest{
+			Server: serverId,
+			Player: "p1",
+			Reason: service.LeaveReasonQuit,
+		})
+		assert.Equal(t, fiber.StatusNotFound, res.StatusCode())
+
+		// Other servers can't remove the player
+		res = post(t, "/api/players/leave", players_routes.LeavePlayerRequest{
+			Server: 2,
+			Player: "p3",
+			Reason: service.LeaveReasonKicked,
+		})
+		assert.Equal(t, fiber.StatusNotFound, res.StatusCode())
+		assert.True(t, service.IsOnServerOrWaiting("p3"))
+	})
+
+	t.Run("bulk leave removes everyone it can", func(t *testing.T) {
+		res := post(t, "/api/players/leave_bulk", players_routes.LeaveBulkRequest{
+			Server: serverId,
+			Players: []service.PlayerLeave{
+				{Player: "p3", Reason: service.LeaveReasonMatchEnded},
+				{Player: "p4", Reason: service.LeaveReasonMatchEnded, Retire: true},
+				{Player: "nobody", Reason: service.LeaveReasonQuit},
+			},
+		})
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+
+		var r players_routes.LeaveBulkResponse
+		testing_util.Unmarshal(t, res.Bytes(), &r)
+		assert.Equal(t, []string{"nobody"}, r.Unknown)
+		assert.False(t, service.IsOnServerOrWaiting("p3"))
+		assert.False(t, service.IsOnServerOrWaiting("p4"))
+		assert.Equal(t, 2, freeTokens(t))
+	})
+}
@@ -7,9 +7,18 @@ import (
 
 func SetupRoutes(router fiber.Router) {
 
-	// Require the credential as a Header
-	router.Use(service.DefaultAuthMiddleware())
+	// Only the server the player is joining can confirm their token (or say they left again)
+	router.Post("/confirm", service.AuthMiddleware(service.RoleGameServer), ConfirmPlayer)
+	router.Post("/leave", service.AuthMiddleware(service.RoleGameServer), LeavePlayer)
+	router.Post("/leave_bulk", service.AuthMiddleware(service.RoleGameServer), LeaveBulk)
+
+	// Lobbies and game servers (e.g. for playing again) can put players into queues
+	router.Use(service.AuthMiddleware(service.RoleLobby, service.RoleGameServer))
 
-	router.Post("/confirm", ConfirmPlayer)
 	router.Post("/queue", QueuePlayer)
+	router.Post("/queue_party", QueueParty)
+	router.Post("/queue_code", QueueCode)
+	router.Post("/queue_status", QueueStatus)
+	router.Post("/queue_cancel", QueueCancel)
+	router.Post("/queue_spectator", QueueSpectator)
 }
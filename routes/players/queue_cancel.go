@@ -0,0 +1,24 @@
+package players_routes
+
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type QueueCancelRequest struct {
+	Player string `json:"player"`
+}
+
+// Route: POST /api/players/queue_cancel
+func QueueCancel(c *fiber.Ctx) error {
+	var req QueueCancelRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	if !service.CancelQueue(req.Player) {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,34 @@
+package players_routes
+
+import (
+	"errors"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type QueueCodeRequest struct {
+	Player   string `json:"player"`
+	Code     string `json:"code"`               // Join code of the private match
+	Password string `json:"password,omitempty"` // Only needed when the match has one
+}
+
+// Route: POST /api/players/queue_code
+func QueueCode(c *fiber.Ctx) error {
+	var req QueueCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	status, err := service.QueueByCode(req.Code, req.Player, req.Password)
+	switch {
+	case errors.Is(err, service.ErrUnknownJoinCode):
+		return c.SendStatus(fiber.StatusNotFound)
+	case errors.Is(err, service.ErrWrongPassword), errors.Is(err, service.ErrNotAllowed):
+		return c.SendStatus(fiber.StatusForbidden)
+	case err != nil:
+		return c.SendStatus(fiber.StatusConflict)
+	}
+
+	return sendQueueStatus(c, status, req.Player)
+}
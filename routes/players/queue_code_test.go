@@ -0,0 +1,126 @@
+package players_routes_test
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	matches_routes "github.com/Liphium/hytale-matchmaking/routes/matches"
+	players_routes "github.com/Liphium/hytale-matchmaking/routes/players"
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/Liphium/hytale-matchmaking/util"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"resty.dev/v3"
+)
+
+func TestQueueCode(t *testing.T) {
+	service.ResetAll()
+
+	const (
+		serverId = 1
+		game     = "battle"
+	)
+	assert.True(t, service.CreateServer(serverId, "localhost", 3000))
+
+	client := resty.New()
+	defer client.Close()
+	post := func(t *testing.T, path string, body any) *resty.Response {
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			SetBody(body).
+			Post(util.DefaultPath(path))
+		assert.Nil(t, err)
+		return res
+	}
+	advertise := func(t *testing.T, match service.MatchCreate) string {
+		res := post(t, "/api/matches/advertise", matches_routes.AdvertiseMatchRequest{
+			Server: serverId,
+			Match:  match,
+			Tokens: []string{"a", "b", "c"},
+		})
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+		assert.True(t, service.SetMatchState(serverId, match.ID, service.MatchStateAccepting))
+
+		var r matches_routes.AdvertiseMatchResponse
+		testing_util.Unmarshal(t, res.Bytes(), &r)
+		return r.JoinCode
+	}
+	queueCode := func(t *testing.T, player string, code string, password string) (int, players_routes.QueuePlayerResponse) {
+		res := post(t, "/api/players/queue_code", players_routes.QueueCodeRequest{
+			Player:   player,
+			Code:     code,
+			Password: password,
+		})
+		var r players_routes.QueuePlayerResponse
+		if res.StatusCode() == fiber.StatusOK {
+			testing_util.Unmarshal(t, res.Bytes(), &r)
+		}
+		return res.StatusCode(), r
+	}
+
+	open := advertise(t, service.MatchCreate{ID: 1, Game: game, Private: true})
+	locked := advertise(t, service.MatchCreate{ID: 2, Game: game, Private: true, Password: "secret", Allowlist: []string{"friend", "stranger"}})
+	assert.Len(t, open, service.JoinCodeLength)
+	assert.NotEqual(t, open, locked)
+
+	t.Run("private matches are never picked", func(t *testing.T) {
+		status, ok := service.QueuePlayer(game, "random")
+		assert.True(t, ok)
+		assert.False(t, status.Assigned)
+		assert.True(t, service.CancelQueue("random"))
+	})
+
+	t.Run("players can join using the code", func(t *testing.T) {
+		status, r := queueCode(t, "player", open, "")
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, 3000, r.Port)
+
+		lookup, ok := service.LookupPlayer("player")
+		assert.True(t, ok)
+		assert.Equal(t, 1, lookup.Player.Match)
+
+		status, _ = queueCode(t, "player", open, "")
+		assert.Equal(t, fiber.StatusConflict, status)
+	})
+
+	t.Run("players only get one slot when joining at the same time", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		for range 10 {
+			wg.Go(func() {
+				service.QueueByCode(open, "twice", "")
+			})
+		}
+		wg.Wait()
+
+		match, ok := service.GetMatchFromServer(serverId, 1)
+		assert.True(t, ok)
+		match.Mutex.RLock()
+		players := slices.Clone(match.Players)
+		match.Mutex.RUnlock()
+		assert.Len(t, slices.DeleteFunc(players, func(p string) bool { return p != "twice" }), 1)
+	})
+
+	t.Run("unknown codes are rejected", func(t *testing.T) {
+		status, _ := queueCode(t, "other", "nope", "")
+		assert.Equal(t, fiber.StatusNotFound, status)
+	})
+
+	t.Run("password and allowlist are checked", func(t *testing.T) {
+		status, _ := queueCode(t, "friend", locked, "wrong")
+		assert.Equal(t, fiber.StatusForbidden, status)
+		status, _ = queueCode(t, "someone", locked, "secret")
+		assert.Equal(t, fiber.StatusForbidden, status)
+
+		status, r := queueCode(t, "friend", locked, "secret")
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.NotEmpty(t, r.Token)
+	})
+
+	t.Run("codes stop working once the match ended", func(t *testing.T) {
+		assert.True(t, service.SetMatchState(serverId, 2, service.MatchStateEnd))
+		status, _ := queueCode(t, "stranger", locked, "secret")
+		assert.Equal(t, fiber.StatusNotFound, status)
+	})
+}
@@ -0,0 +1,37 @@
+package players_routes
+
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type QueuePartyRequest struct {
+	Players []string `json:"players"`
+	Game    string   `json:"game"`
+
+	// Same as for /api/players/queue (the pings should be for the whole party, e.g. the worst of everyone)
+	Region string         `json:"region,omitempty"`
+	Pings  map[string]int `json:"pings,omitempty"`
+}
+
+// Route: POST /api/players/queue_party (responds with the same as /api/players/queue, tokens for all members are in tokens)
+func QueueParty(c *fiber.Ctx) error {
+	var req QueuePartyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	if len(req.Players) == 0 {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	// Put the whole party into the queue (they are assigned to the same match right away if possible)
+	status, ok := service.QueuePartyWithPreference(req.Game, req.Players, service.RegionPreference{
+		Region: req.Region,
+		Pings:  req.Pings,
+	})
+	if !ok {
+		return c.SendStatus(fiber.StatusConflict)
+	}
+
+	return sendQueueStatus(c, status, req.Players[0])
+}
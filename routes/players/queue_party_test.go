@@ -0,0 +1,111 @@
+package players_routes_test
+
+import (
+	"testing"
+	"time"
+
+	players_routes "github.com/Liphium/hytale-matchmaking/routes/players"
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/Liphium/hytale-matchmaking/util"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"resty.dev/v3"
+)
+
+func TestPartyQueuing(t *testing.T) {
+	service.ResetAll()
+
+	// Create a test server with a match that only has room for two more players
+	const (
+		serverId = 1
+		server   = "localhost"
+		port     = 3000
+		game     = "battle"
+	)
+	assert.True(t, service.CreateServer(serverId, server, port))
+	assert.True(t, service.AddMatch(serverId, service.MatchCreate{
+		ID:   1,
+		Game: game,
+	}, []string{"a", "b", "c"}))
+	assert.True(t, service.SetMatchState(serverId, 1, service.MatchStateAccepting))
+	_, _, ok := service.CreatePlayerIfPossible(game, "solo")
+	assert.True(t, ok)
+
+	party := []string{"friend1", "friend2", "friend3"}
+
+	t.Run("party waits for a match with enough slots", func(t *testing.T) {
+		client := resty.New()
+		defer client.Close()
+
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			SetBody(players_routes.QueuePartyRequest{
+				Players: party,
+				Game:    game,
+			}).
+			Post(util.DefaultPath("/api/players/queue_party"))
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusAccepted, res.StatusCode())
+
+		// Nobody from the party should have been put into the first match
+		match, ok := service.GetMatchFromServer(serverId, 1)
+		assert.True(t, ok)
+		assert.Equal(t, []string{"solo"}, match.Players)
+	})
+
+	t.Run("party gets placed together", func(t *testing.T) {
+		assert.True(t, service.AddMatch(serverId, service.MatchCreate{
+			ID:   2,
+			Game: game,
+		}, []string{"d", "e", "f", "g"}))
+		assert.True(t, service.SetMatchState(serverId, 2, service.MatchStateAccepting))
+
+		client := resty.New()
+		defer client.Close()
+
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			SetBody(players_routes.QueueStatusRequest{
+				Player: "friend2",
+			}).
+			Post(util.DefaultPath("/api/players/queue_status"))
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+
+		var r players_routes.QueuePlayerResponse
+		testing_util.Unmarshal(t, res.Bytes(), &r)
+		assert.Equal(t, server, r.Address)
+		assert.Equal(t, "e", r.Token)
+		assert.Equal(t, map[string]string{
+			"friend1": "d",
+			"friend2": "e",
+			"friend3": "f",
+		}, r.Tokens)
+
+		match, ok := service.GetMatchFromServer(serverId, 2)
+		assert.True(t, ok)
+		assert.Equal(t, party, match.Players)
+	})
+
+	t.Run("reservations are rolled back when a member doesn't join", func(t *testing.T) {
+		_, ok := service.ConfirmPlayerToken(serverId, "friend1", "d")
+		assert.True(t, ok)
+
+		match, ok := service.GetMatchFromServer(serverId, 2)
+		assert.True(t, ok)
+
+		// Wait for the reservations of the other members to actually time out
+		assert.Eventually(t, func() bool {
+			match.Mutex.RLock()
+			defer match.Mutex.RUnlock()
+			return len(match.TokenStore) == 3
+		}, service.PlayerTokenTimeout+15*time.Second, 100*time.Millisecond)
+
+		assert.True(t, service.IsOnServerOrWaiting("friend1"))
+		assert.False(t, service.IsOnServerOrWaiting("friend2"))
+		match.Mutex.RLock()
+		defer match.Mutex.RUnlock()
+		assert.Equal(t, []string{"friend1"}, match.Players)
+	})
+}
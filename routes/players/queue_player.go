@@ -8,12 +8,27 @@ import (
 type QueuePlayerRequest struct {
 	Player string `json:"player"`
 	Game   string `json:"game"`
+
+	// Where the player would like to play (both are optional, the region is preferred over the pings)
+	Region string         `json:"region,omitempty"`
+	Pings  map[string]int `json:"pings,omitempty"` // Region -> ping in milliseconds
 }
 
 type QueuePlayerResponse struct {
-	Address string `json:"address"` // Address of the server (e.g. liphium.com or 127.0.0.1)
-	Port    int    `json:"port"`
-	Token   string `json:"token"`
+	Address string `json:"address,omitempty"` // Address of the server (e.g. liphium.com or 127.0.0.1)
+	Port    int    `json:"port,omitempty"`
+	Token   string `json:"token,omitempty"`
+	Ticket  string `json:"ticket,omitempty"` // Signed join ticket the server can verify without asking the matchmaker
+	Region  string `json:"region,omitempty"` // Region of the server the player was sent to
+	Rejoin  bool   `json:"rejoin,omitempty"` // Whether the player is sent back into the match they disconnected from
+
+	// Account -> token/ticket (only set for parties)
+	Tokens  map[string]string `json:"tokens,omitempty"`
+	Tickets map[string]string `json:"tickets,omitempty"`
+
+	// Only set while the player is still waiting for a slot (status 202)
+	Position      int `json:"position,omitempty"`       // Position in the queue (starting at 1)
+	EstimatedWait int `json:"estimated_wait,omitempty"` // In seconds (0 when there is no estimate yet)
 }
 
 // Route: POST /api/players/queue
@@ -23,19 +38,50 @@ func QueuePlayer(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusBadRequest)
 	}
 
-	token, server, ok := service.CreatePlayerIfPossible(req.Game, req.Player)
+	// Put the player into the queue (they are assigned to a match right away if possible)
+	status, ok := service.QueuePartyWithPreference(req.Game, []string{req.Player}, service.RegionPreference{
+		Region: req.Region,
+		Pings:  req.Pings,
+	})
 	if !ok {
-		return c.SendStatus(fiber.StatusNotFound)
+		return c.SendStatus(fiber.StatusConflict)
+	}
+
+	return sendQueueStatus(c, status, req.Player)
+}
+
+// Helper function for sending the queue status of a player or party to the client
+func sendQueueStatus(c *fiber.Ctx, status service.QueueStatus, player string) error {
+
+	// Tell the client to check back later in case there is no slot yet
+	if !status.Assigned {
+		return c.Status(fiber.StatusAccepted).JSON(QueuePlayerResponse{
+			Position:      status.Position,
+			EstimatedWait: int(status.EstimatedWait.Seconds()),
+		})
 	}
 
-	address, port, ok := service.GetServerDetails(server)
+	address, port, ok := service.GetServerDetails(status.Server)
 	if !ok {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
-	return c.JSON(QueuePlayerResponse{
+	res := QueuePlayerResponse{
 		Address: address,
 		Port:    port,
-		Token:   token,
-	})
+		Token:   status.Tokens[player],
+		Region:  status.Region,
+		Rejoin:  status.Rejoin,
+	}
+	res.Ticket, _ = service.IssueJoinTicket(player)
+	if len(status.Tokens) > 1 {
+		res.Tokens = status.Tokens
+		res.Tickets = map[string]string{}
+		for account := range status.Tokens {
+			if ticket, ok := service.IssueJoinTicket(account); ok {
+				res.Tickets[account] = ticket
+			}
+		}
+	}
+	return c.JSON(res)
 }
@@ -1,6 +1,7 @@
 package players_routes_test
 
 import (
+	"sync"
 	"testing"
 
 	players_routes "github.com/Liphium/hytale-matchmaking/routes/players"
@@ -52,7 +53,7 @@ func TestPlayerQueuing(t *testing.T) {
 		assert.Equal(t, port, r.Port)
 	})
 
-	t.Run("queueing another player fails", func(t *testing.T) {
+	t.Run("another player has to wait", func(t *testing.T) {
 		client := resty.New()
 		defer client.Close()
 
@@ -64,7 +65,12 @@ func TestPlayerQueuing(t *testing.T) {
 			}).
 			Post(util.DefaultPath("/api/players/queue"))
 		assert.Nil(t, err)
-		assert.Equal(t, fiber.StatusNotFound, res.StatusCode())
+		assert.Equal(t, fiber.StatusAccepted, res.StatusCode())
+
+		var r players_routes.QueuePlayerResponse
+		testing_util.Unmarshal(t, res.Bytes(), &r)
+		assert.Equal(t, 1, r.Position)
+		assert.Empty(t, r.Token)
 	})
 
 	t.Run("queueing the same player fails", func(t *testing.T) {
@@ -79,6 +85,20 @@ func TestPlayerQueuing(t *testing.T) {
 			}).
 			Post(util.DefaultPath("/api/players/queue"))
 		assert.Nil(t, err)
-		assert.Equal(t, fiber.StatusNotFound, res.StatusCode())
+		assert.Equal(t, fiber.StatusConflict, res.StatusCode())
+	})
+
+	t.Run("queueing the same player at the same time only adds them once", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		for range 10 {
+			wg.Go(func() {
+				service.QueuePlayer(game, "test3")
+			})
+		}
+		wg.Wait()
+
+		queues := service.ListQueues()
+		assert.Len(t, queues, 1)
+		assert.Equal(t, 2, queues[0].Players)
 	})
 }
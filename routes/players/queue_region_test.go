@@ -0,0 +1,94 @@
+package players_routes_test
+
+import (
+	"testing"
+	"time"
+
+	players_routes "github.com/Liphium/hytale-matchmaking/routes/players"
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/Liphium/hytale-matchmaking/util"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"resty.dev/v3"
+)
+
+func TestRegionQueuing(t *testing.T) {
+	service.ResetAll()
+
+	// One server in Europe and one in North America
+	const (
+		eu   = 1
+		na   = 2
+		game = "battle"
+	)
+	assert.True(t, service.CreateServerInRegion(eu, "localhost", 3000, "eu", nil))
+	assert.True(t, service.CreateServerInRegion(na, "localhost", 3001, "na", []string{"beta"}))
+	for _, server := range []int{eu, na} {
+		assert.True(t, service.AddMatch(server, service.MatchCreate{
+			ID:   1,
+			Game: game,
+		}, []string{"a", "b", "c", "d"}))
+		assert.True(t, service.SetMatchState(server, 1, service.MatchStateAccepting))
+	}
+
+	queue := func(t *testing.T, req players_routes.QueuePlayerRequest) (int, players_routes.QueuePlayerResponse) {
+		client := resty.New()
+		defer client.Close()
+
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			SetBody(req).
+			Post(util.DefaultPath("/api/players/queue"))
+		assert.Nil(t, err)
+
+		var r players_routes.QueuePlayerResponse
+		testing_util.Unmarshal(t, res.Bytes(), &r)
+		return res.StatusCode(), r
+	}
+
+	t.Run("preferred region is used", func(t *testing.T) {
+		status, r := queue(t, players_routes.QueuePlayerRequest{
+			Player: "american",
+			Game:   game,
+			Region: "na",
+		})
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, "na", r.Region)
+		assert.Equal(t, 3001, r.Port)
+	})
+
+	t.Run("region with the lowest ping is used", func(t *testing.T) {
+		status, r := queue(t, players_routes.QueuePlayerRequest{
+			Player: "european",
+			Game:   game,
+			Pings:  map[string]int{"na": 120, "eu": 30},
+		})
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, "eu", r.Region)
+	})
+
+	t.Run("players without a server close to them have to wait", func(t *testing.T) {
+		status, r := queue(t, players_routes.QueuePlayerRequest{
+			Player: "australian",
+			Game:   game,
+			Region: "oce",
+		})
+		assert.Equal(t, fiber.StatusAccepted, status)
+		assert.Equal(t, 1, r.Position)
+	})
+
+	t.Run("other regions are used after waiting", func(t *testing.T) {
+		service.SetRegionFallbackWait(50 * time.Millisecond)
+		defer service.SetRegionFallbackWait(service.DefaultRegionFallbackWait)
+		time.Sleep(100 * time.Millisecond)
+
+		// Changing the state gives the queue a chance to move
+		assert.True(t, service.SetMatchState(na, 1, service.MatchStateAccepting))
+
+		status, ok := service.GetQueueStatus("australian")
+		assert.True(t, ok)
+		assert.True(t, status.Assigned)
+		assert.NotEmpty(t, status.Region)
+	})
+}
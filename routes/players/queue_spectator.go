@@ -0,0 +1,60 @@
+package players_routes
+
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Only one way to pick the match should be used (server and match, game or follow)
+type QueueSpectatorRequest struct {
+	Player string `json:"player"`
+	Server int    `json:"server,omitempty"` // Server of the match to spectate
+	Match  int    `json:"match,omitempty"`
+	Game   string `json:"game,omitempty"`   // Spectate the fullest match of a game
+	Follow string `json:"follow,omitempty"` // Spectate the match of another player
+}
+
+type QueueSpectatorResponse struct {
+	Address string `json:"address"` // Address of the server (e.g. liphium.com or 127.0.0.1)
+	Port    int    `json:"port"`
+	Token   string `json:"token"`
+	Ticket  string `json:"ticket,omitempty"` // Signed join ticket (see /api/players/queue)
+	Match   int    `json:"match"`
+}
+
+// Route: POST /api/players/queue_spectator
+func QueueSpectator(c *fiber.Ctx) error {
+	var req QueueSpectatorRequest
+	if err := c.BodyParser(&req); err != nil || req.Player == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	// Make sure the player isn't in a queue already
+	if service.IsOnServerOrWaiting(req.Player) {
+		return c.SendStatus(fiber.StatusConflict)
+	}
+
+	token, server, match, ok := service.CreateSpectatorIfPossible(service.SpectateTarget{
+		Server: req.Server,
+		Match:  req.Match,
+		Game:   req.Game,
+		Follow: req.Follow,
+	}, req.Player)
+	if !ok {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	address, port, ok := service.GetServerDetails(server)
+	if !ok {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	ticket, _ := service.IssueJoinTicket(req.Player)
+	return c.JSON(QueueSpectatorResponse{
+		Address: address,
+		Port:    port,
+		Token:   token,
+		Ticket:  ticket,
+		Match:   match,
+	})
+}
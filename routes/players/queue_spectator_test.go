@@ -0,0 +1,149 @@
+package players_routes_test
+
+import (
+	"testing"
+
+	players_routes "github.com/Liphium/hytale-matchmaking/routes/players"
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/Liphium/hytale-matchmaking/util"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"resty.dev/v3"
+)
+
+func TestSpectating(t *testing.T) {
+	service.ResetAll()
+
+	// Create a test server with a full match that has two spectator slots
+	const (
+		serverId = 1
+		server   = "localhost"
+		port     = 3000
+		game     = "battle"
+	)
+	assert.True(t, service.CreateServer(serverId, server, port))
+	assert.True(t, service.AddMatch(serverId, service.MatchCreate{
+		ID:              1,
+		Game:            game,
+		SpectatorTokens: []string{"s1", "s2"},
+	}, []string{"a"}))
+	assert.True(t, service.SetMatchState(serverId, 1, service.MatchStateAccepting))
+	_, _, ok := service.CreatePlayerIfPossible(game, "player")
+	assert.True(t, ok)
+	assert.True(t, service.SetMatchState(serverId, 1, service.MatchStateFull))
+
+	spectate := func(t *testing.T, req players_routes.QueueSpectatorRequest) (int, players_routes.QueueSpectatorResponse) {
+		client := resty.New()
+		defer client.Close()
+
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			SetBody(req).
+			Post(util.DefaultPath("/api/players/queue_spectator"))
+		assert.Nil(t, err)
+
+		var r players_routes.QueueSpectatorResponse
+		if res.StatusCode() == fiber.StatusOK {
+			testing_util.Unmarshal(t, res.Bytes(), &r)
+		}
+		return res.StatusCode(), r
+	}
+
+	t.Run("spectate a match by id", func(t *testing.T) {
+		status, r := spectate(t, players_routes.QueueSpectatorRequest{
+			Player: "viewer1",
+			Server: serverId,
+			Match:  1,
+		})
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, "s1", r.Token)
+		assert.Equal(t, port, r.Port)
+
+		// Spectators don't take player slots
+		match, ok := service.GetMatchFromServer(serverId, 1)
+		assert.True(t, ok)
+		assert.Equal(t, []string{"player"}, match.Players)
+		assert.Equal(t, []string{"viewer1"}, match.Spectators)
+	})
+
+	t.Run("confirming marks the player as a spectator", func(t *testing.T) {
+		client := resty.New()
+		defer client.Close()
+
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			SetBody(players_routes.ConfirmPlayerRequest{
+				Server: serverId,
+				Player: "viewer1",
+				Token:  "s1",
+			}).
+			Post(util.DefaultPath("/api/players/confirm"))
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+
+		var r players_routes.ConfirmPlayerResponse
+		testing_util.Unmarshal(t, res.Bytes(), &r)
+		assert.Equal(t, 1, r.Match)
+		assert.True(t, r.Spectator)
+	})
+
+	t.Run("follow a player", func(t *testing.T) {
+		status, r := spectate(t, players_routes.QueueSpectatorRequest{
+			Player: "viewer2",
+			Follow: "player",
+		})
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, "s2", r.Token)
+		assert.Equal(t, 1, r.Match)
+	})
+
+	t.Run("no spectator slots left", func(t *testing.T) {
+		status, _ := spectate(t, players_routes.QueueSpectatorRequest{
+			Player: "viewer3",
+			Game:   game,
+		})
+		assert.Equal(t, fiber.StatusNotFound, status)
+	})
+
+	t.Run("leaving spectators free their slot", func(t *testing.T) {
+		service.DeletePlayer("viewer2", nil)
+
+		status, r := spectate(t, players_routes.QueueSpectatorRequest{
+			Player: "viewer3",
+			Game:   game,
+		})
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, "s2", r.Token)
+
+		match, ok := service.GetMatchFromServer(serverId, 1)
+		assert.True(t, ok)
+		assert.Empty(t, match.TokenStore)
+	})
+
+	t.Run("private matches can't be spectated by id", func(t *testing.T) {
+		assert.True(t, service.AddMatch(serverId, service.MatchCreate{
+			ID:              2,
+			Game:            game,
+			Private:         true,
+			SpectatorTokens: []string{"s3"},
+		}, []string{"b"}))
+		assert.True(t, service.SetMatchState(serverId, 2, service.MatchStateFull))
+
+		status, _ := spectate(t, players_routes.QueueSpectatorRequest{
+			Player: "stranger",
+			Server: serverId,
+			Match:  2,
+		})
+		assert.Equal(t, fiber.StatusNotFound, status)
+		assert.False(t, service.IsOnServerOrWaiting("stranger"))
+	})
+
+	t.Run("spectators can't queue twice", func(t *testing.T) {
+		status, _ := spectate(t, players_routes.QueueSpectatorRequest{
+			Player: "viewer1",
+			Game:   game,
+		})
+		assert.Equal(t, fiber.StatusConflict, status)
+	})
+}
@@ -0,0 +1,25 @@
+package players_routes
+
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type QueueStatusRequest struct {
+	Player string `json:"player"`
+}
+
+// Route: POST /api/players/queue_status (responds with the same as /api/players/queue)
+func QueueStatus(c *fiber.Ctx) error {
+	var req QueueStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	status, ok := service.GetQueueStatus(req.Player)
+	if !ok {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	return sendQueueStatus(c, status, req.Player)
+}
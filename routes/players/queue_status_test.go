@@ -0,0 +1,106 @@
+package players_routes_test
+
+import (
+	"testing"
+
+	players_routes "github.com/Liphium/hytale-matchmaking/routes/players"
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/Liphium/hytale-matchmaking/util"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"resty.dev/v3"
+)
+
+func TestQueueStatus(t *testing.T) {
+	service.ResetAll()
+
+	// Create a test server and a match that isn't accepting players yet
+	const (
+		serverId = 1
+		server   = "localhost"
+		port     = 3000
+		game     = "battle"
+		matchId  = 1
+	)
+	assert.True(t, service.CreateServer(serverId, server, port))
+	assert.True(t, service.AddMatch(serverId, service.MatchCreate{
+		ID:   matchId,
+		Game: game,
+	}, []string{"test"}))
+
+	queueStatus := func(t *testing.T, player string) (int, players_routes.QueuePlayerResponse) {
+		client := resty.New()
+		defer client.Close()
+
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			SetBody(players_routes.QueueStatusRequest{
+				Player: player,
+			}).
+			Post(util.DefaultPath("/api/players/queue_status"))
+		assert.Nil(t, err)
+
+		var r players_routes.QueuePlayerResponse
+		if len(res.Bytes()) > 0 && res.StatusCode() != fiber.StatusNotFound {
+			testing_util.Unmarshal(t, res.Bytes(), &r)
+		}
+		return res.StatusCode(), r
+	}
+
+	t.Run("players keep their position", func(t *testing.T) {
+		for i, player := range []string{"first", "second", "third"} {
+			status, ok := service.QueuePlayer(game, player)
+			assert.True(t, ok)
+			assert.False(t, status.Assigned)
+			assert.Equal(t, i+1, status.Position)
+		}
+
+		code, r := queueStatus(t, "second")
+		assert.Equal(t, fiber.StatusAccepted, code)
+		assert.Equal(t, 2, r.Position)
+	})
+
+	t.Run("cancelled players leave the queue", func(t *testing.T) {
+		client := resty.New()
+		defer client.Close()
+
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			SetBody(players_routes.QueueCancelRequest{
+				Player: "first",
+			}).
+			Post(util.DefaultPath("/api/players/queue_cancel"))
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+
+		code, _ := queueStatus(t, "first")
+		assert.Equal(t, fiber.StatusNotFound, code)
+
+		code, r := queueStatus(t, "second")
+		assert.Equal(t, fiber.StatusAccepted, code)
+		assert.Equal(t, 1, r.Position)
+	})
+
+	t.Run("first player gets assigned when the match accepts players", func(t *testing.T) {
+		assert.True(t, service.SetMatchState(serverId, matchId, service.MatchStateAccepting))
+
+		code, r := queueStatus(t, "second")
+		assert.Equal(t, fiber.StatusOK, code)
+		assert.Equal(t, "test", r.Token)
+		assert.Equal(t, server, r.Address)
+		assert.Equal(t, port, r.Port)
+
+		code, r = queueStatus(t, "third")
+		assert.Equal(t, fiber.StatusAccepted, code)
+		assert.Equal(t, 1, r.Position)
+	})
+
+	t.Run("freed slots go to the next player", func(t *testing.T) {
+		service.DeletePlayer("second", nil)
+
+		code, r := queueStatus(t, "third")
+		assert.Equal(t, fiber.StatusOK, code)
+		assert.Equal(t, "test", r.Token)
+	})
+}
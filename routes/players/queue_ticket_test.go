@@ -0,0 +1,83 @@
+package players_routes_test
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+
+	players_routes "github.com/Liphium/hytale-matchmaking/routes/players"
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/Liphium/hytale-matchmaking/util"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"resty.dev/v3"
+)
+
+func TestQueueWithTicket(t *testing.T) {
+	service.ResetAll()
+
+	const (
+		id   = 1
+		game = "battle"
+	)
+
+	// Match without any tokens (the server only verifies tickets)
+	assert.True(t, service.CreateServer(id, "localhost", 3000))
+	assert.True(t, service.AddMatch(id, service.MatchCreate{
+		ID:         1,
+		Game:       game,
+		MaxPlayers: 4,
+	}, nil))
+	service.SetMatchState(id, 1, service.MatchStateAccepting)
+	audience, ok := service.GetTicketAudience(id)
+	assert.True(t, ok)
+
+	client := resty.New()
+	defer client.Close()
+
+	// Servers fetch the key without any credential
+	var key ed25519.PublicKey
+	t.Run("key set is public", func(t *testing.T) {
+		res, err := client.R().Get(util.DefaultPath("/api/servers/jwks"))
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+
+		var jwks service.JWKS
+		testing_util.Unmarshal(t, res.Bytes(), &jwks)
+		if assert.Len(t, jwks.Keys, 1) {
+			assert.Equal(t, "EdDSA", jwks.Keys[0].Algorithm)
+			key, err = base64.RawURLEncoding.DecodeString(jwks.Keys[0].X)
+			assert.Nil(t, err)
+		}
+	})
+
+	t.Run("tickets are verified offline and confirmed", func(t *testing.T) {
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			SetBody(players_routes.QueuePartyRequest{
+				Players: []string{"leader", "member"},
+				Game:    game,
+			}).
+			Post(util.DefaultPath("/api/players/queue_party"))
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+
+		var r players_routes.QueuePlayerResponse
+		testing_util.Unmarshal(t, res.Bytes(), &r)
+		assert.Equal(t, r.Tickets["leader"], r.Ticket)
+		assert.Len(t, r.Tickets, 2)
+
+		for account, signed := range r.Tickets {
+			ticket, err := service.VerifyJoinTicket(signed, key, audience)
+			assert.Nil(t, err)
+			assert.Equal(t, account, ticket.Account)
+			assert.Equal(t, r.Tokens[account], ticket.Token)
+
+			// The server confirms with the token from the ticket
+			match, ok := service.ConfirmPlayerToken(id, ticket.Account, ticket.Token)
+			assert.True(t, ok)
+			assert.Equal(t, 1, match)
+		}
+	})
+}
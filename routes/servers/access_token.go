@@ -0,0 +1,42 @@
+package servers_routes
+
+import (
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AccessTokenRequest struct {
+	ID int `json:"id"`
+}
+
+type AccessTokenResponse struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"` // Zero when unknown
+}
+
+// Endpoint: /api/servers/access_token (for getting the token after an access_token_rotated event)
+func getAccessToken(c *fiber.Ctx) error {
+	var req AccessTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	if !service.OwnsServer(c, req.ID) {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+	if _, _, ok := service.GetServerDetails(req.ID); !ok {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	// Servers use the token with the same id
+	token, ok := service.GetToken(req.ID)
+	if !ok {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	return c.JSON(AccessTokenResponse{
+		AccessToken: token.AccessToken,
+		ExpiresAt:   token.ExpiresAt,
+	})
+}
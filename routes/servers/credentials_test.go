@@ -0,0 +1,179 @@
+package servers_routes_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	matches_routes "github.com/Liphium/hytale-matchmaking/routes/matches"
+	players_routes "github.com/Liphium/hytale-matchmaking/routes/players"
+	servers_routes "github.com/Liphium/hytale-matchmaking/routes/servers"
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/Liphium/hytale-matchmaking/util"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"resty.dev/v3"
+)
+
+func TestServerCredentials(t *testing.T) {
+	service.ResetAll()
+	t.Setenv("TOKEN_FILE_LOCATION", t.TempDir())
+	t.Setenv("REGISTRATION_CREDENTIAL", "registration")
+	t.Setenv("LOBBY_CREDENTIAL", "lobby")
+
+	// Local stand-in for the Hytale session endpoint
+	sessions := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(service.GameSessionResponse{
+			SessionToken:  "session",
+			IdentityToken: "identity",
+			ExpiresAt:     time.Now().Add(time.Hour),
+		})
+	}))
+	defer sessions.Close()
+	t.Setenv("HYTALE_SESSION_URL", sessions.URL)
+
+	service.AddToken(service.Token{AccessToken: "first"})
+	service.AddToken(service.Token{AccessToken: "second"})
+
+	client := resty.New()
+	defer client.Close()
+
+	post := func(t *testing.T, credential string, path string, body any) *resty.Response {
+		res, err := client.R().
+			SetHeader("Credential", credential).
+			SetBody(body).
+			Post(util.DefaultPath(path))
+		assert.Nil(t, err)
+		return res
+	}
+
+	register := func(t *testing.T, port int) servers_routes.RegisterServerResponse {
+		res := post(t, "registration", "/api/servers/register", servers_routes.RegisterServerRequest{
+			IP:   "localhost",
+			Port: port,
+		})
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+
+		assert.NotContains(t, string(res.Bytes()), "refresh_token") // Only the matchmaker refreshes tokens
+
+		var r servers_routes.RegisterServerResponse
+		testing_util.Unmarshal(t, res.Bytes(), &r)
+		assert.True(t, strings.HasPrefix(r.Secret, strconv.Itoa(r.ID)+"."))
+		assert.True(t, strings.HasPrefix(r.Audience, strconv.Itoa(r.ID)+"."))
+		return r
+	}
+	first := register(t, 3000)
+	second := register(t, 3001)
+
+	t.Run("unknown credentials are rejected", func(t *testing.T) {
+		res := post(t, "nope", "/api/servers/renew", servers_routes.RenewServerRequest{ID: first.ID})
+		assert.Equal(t, fiber.StatusUnauthorized, res.StatusCode())
+
+		res = post(t, strconv.Itoa(first.ID)+".nope", "/api/servers/renew", servers_routes.RenewServerRequest{ID: first.ID})
+		assert.Equal(t, fiber.StatusUnauthorized, res.StatusCode())
+	})
+
+	t.Run("servers can only manage themselves", func(t *testing.T) {
+		res := post(t, first.Secret, "/api/servers/renew", servers_routes.RenewServerRequest{ID: first.ID})
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+
+		res = post(t, first.Secret, "/api/servers/renew", servers_routes.RenewServerRequest{ID: second.ID})
+		assert.Equal(t, fiber.StatusForbidden, res.StatusCode())
+
+		res = post(t, first.Secret, "/api/servers/set_access_token", servers_routes.SetTokenRequest{Id: second.ID, AccessToken: "stolen"})
+		assert.Equal(t, fiber.StatusForbidden, res.StatusCode())
+		token, _ := service.GetToken(second.ID)
+		assert.Equal(t, second.AccessToken, token.AccessToken)
+
+		res = post(t, first.Secret, "/api/matches/advertise", matches_routes.AdvertiseMatchRequest{
+			Server: second.ID,
+			Match:  service.MatchCreate{ID: 1, Game: "battle"},
+			Tokens: []string{"a"},
+		})
+		assert.Equal(t, fiber.StatusForbidden, res.StatusCode())
+
+		res = post(t, first.Secret, "/api/matches/advertise", matches_routes.AdvertiseMatchRequest{
+			Server: first.ID,
+			Match:  service.MatchCreate{ID: 1, Game: "battle"},
+			Tokens: []string{"a"},
+		})
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+
+		res = post(t, second.Secret, "/api/matches/set_state", matches_routes.MatchSetStateRequest{
+			Server: first.ID,
+			Match:  1,
+			State:  service.MatchStateEnd,
+		})
+		assert.Equal(t, fiber.StatusForbidden, res.StatusCode())
+
+		res = post(t, second.Secret, "/api/players/confirm", players_routes.ConfirmPlayerRequest{
+			Server: first.ID,
+			Player: "player",
+			Token:  "a",
+		})
+		assert.Equal(t, fiber.StatusForbidden, res.StatusCode())
+	})
+
+	t.Run("servers can only get their own access token", func(t *testing.T) {
+		res := post(t, first.Secret, "/api/servers/access_token", servers_routes.AccessTokenRequest{ID: first.ID})
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+		var r servers_routes.AccessTokenResponse
+		testing_util.Unmarshal(t, res.Bytes(), &r)
+		assert.Equal(t, first.AccessToken, r.AccessToken)
+
+		res = post(t, first.Secret, "/api/servers/access_token", servers_routes.AccessTokenRequest{ID: second.ID})
+		assert.Equal(t, fiber.StatusForbidden, res.StatusCode())
+	})
+
+	t.Run("setting an access token replaces its expiry", func(t *testing.T) {
+		res := post(t, second.Secret, "/api/servers/set_access_token", servers_routes.SetTokenRequest{
+			Id:          second.ID,
+			AccessToken: "replaced",
+			ExpiresIn:   120,
+		})
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+
+		token, _ := service.GetToken(second.ID)
+		assert.Equal(t, "replaced", token.AccessToken)
+		assert.WithinDuration(t, time.Now().Add(2*time.Minute), token.ExpiresAt, 5*time.Second)
+
+		// Without an expiry the default lifetime is assumed
+		res = post(t, second.Secret, "/api/servers/set_access_token", servers_routes.SetTokenRequest{Id: second.ID, AccessToken: "again"})
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+		token, _ = service.GetToken(second.ID)
+		assert.WithinDuration(t, time.Now().Add(service.DefaultTokenLifetime), token.ExpiresAt, 5*time.Second)
+	})
+
+	t.Run("lobbies can queue players but not manage servers", func(t *testing.T) {
+		res := post(t, "lobby", "/api/players/queue", players_routes.QueuePlayerRequest{
+			Player: "player",
+			Game:   "battle",
+		})
+		assert.Equal(t, fiber.StatusAccepted, res.StatusCode()) // Nothing is accepting players, so they're queued
+
+		res = post(t, "lobby", "/api/servers/renew", servers_routes.RenewServerRequest{ID: first.ID})
+		assert.Equal(t, fiber.StatusForbidden, res.StatusCode())
+		res = post(t, "lobby", "/api/servers/register", servers_routes.RegisterServerRequest{IP: "localhost"})
+		assert.Equal(t, fiber.StatusForbidden, res.StatusCode())
+	})
+
+	t.Run("registration credential can only register", func(t *testing.T) {
+		res := post(t, "registration", "/api/servers/renew", servers_routes.RenewServerRequest{ID: first.ID})
+		assert.Equal(t, fiber.StatusForbidden, res.StatusCode())
+	})
+
+	t.Run("registering again replaces the secret", func(t *testing.T) {
+		service.EvictServer(first.ID)
+		again := register(t, 3000)
+		assert.Equal(t, first.ID, again.ID)
+		assert.NotEqual(t, first.Secret, again.Secret)
+
+		res := post(t, first.Secret, "/api/servers/renew", servers_routes.RenewServerRequest{ID: first.ID})
+		assert.Equal(t, fiber.StatusUnauthorized, res.StatusCode())
+	})
+}
@@ -0,0 +1,67 @@
+package servers_routes
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// How often a comment is sent to keep the connection alive (and to notice when the server is gone)
+const EventKeepAliveInterval = 15 * time.Second
+
+// Endpoint: /api/servers/events?id=<server id> (server-sent events, see service/events.go for all types)
+func streamEvents(c *fiber.Ctx) error {
+	id := c.QueryInt("id", -1)
+	if !service.OwnsServer(c, id) {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+	if _, _, ok := service.GetServerDetails(id); !ok {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	events, unsubscribe := service.SubscribeToEvents(id)
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		ticker := time.NewTicker(EventKeepAliveInterval)
+		defer ticker.Stop()
+
+		// Tell the client the stream is ready
+		fmt.Fprint(w, ": connected\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+
+				data, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			case <-ticker.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			}
+
+			// Stop when the client disconnected
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
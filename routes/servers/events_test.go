@@ -0,0 +1,121 @@
+package servers_routes_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/Liphium/hytale-matchmaking/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventStream(t *testing.T) {
+	service.ResetAll()
+
+	const (
+		serverId = 1
+		game     = "battle"
+	)
+
+	// The subscribed server has a match, the other one is idle
+	assert.True(t, service.CreateServer(serverId, "localhost", 3000))
+	assert.True(t, service.CreateServer(2, "localhost", 3001))
+	assert.True(t, service.AddMatch(serverId, service.MatchCreate{
+		ID:   1,
+		Game: game,
+	}, []string{"test"}))
+	assert.True(t, service.SetMatchState(serverId, 1, service.MatchStateAccepting))
+
+	// Connect to the event stream
+	req, err := http.NewRequest(http.MethodGet, util.DefaultPath(fmt.Sprintf("/api/servers/events?id=%d", serverId)), nil)
+	assert.Nil(t, err)
+	for key, value := range util.CredentialHeaders() {
+		req.Header.Set(key, value)
+	}
+	res, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	events := make(chan service.Event, 10)
+	connected := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == ": connected" {
+				close(connected)
+			}
+
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				var event service.Event
+				if err := json.Unmarshal([]byte(data), &event); err == nil {
+					events <- event
+				}
+			}
+		}
+	}()
+
+	nextEvent := func(t *testing.T) service.Event {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event received")
+			return service.Event{}
+		}
+	}
+
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream didn't connect")
+	}
+
+	t.Run("reservations are pushed", func(t *testing.T) {
+		_, _, ok := service.CreatePlayerIfPossible(game, "player")
+		assert.True(t, ok)
+
+		event := nextEvent(t)
+		assert.Equal(t, service.EventPlayerReserved, event.Type)
+		assert.Equal(t, map[string]any{
+			"player": "player",
+			"match":  float64(1),
+			"token":  "test",
+		}, event.Data)
+	})
+
+	t.Run("drain requests are pushed", func(t *testing.T) {
+		service.DeletePlayer("player", nil)
+
+		// Make the other server host more matches so the subscribed one gets drained
+		for id := 1; id <= 2; id++ {
+			assert.True(t, service.AddMatch(2, service.MatchCreate{
+				ID:   id,
+				Game: game,
+			}, []string{"other"}))
+		}
+
+		assert.Equal(t, []int{serverId}, service.PlanCapacity().Drain)
+
+		event := nextEvent(t)
+		assert.Equal(t, service.EventDrainRequested, event.Type)
+	})
+
+	t.Run("unknown servers can't subscribe", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, util.DefaultPath("/api/servers/events?id=67"), nil)
+		assert.Nil(t, err)
+		for key, value := range util.CredentialHeaders() {
+			req.Header.Set(key, value)
+		}
+		res, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
@@ -0,0 +1,11 @@
+package servers_routes
+
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Endpoint: /api/servers/jwks (public key for verifying join tickets, servers only need to fetch it once)
+func getJWKS(c *fiber.Ctx) error {
+	return c.JSON(service.GetJWKS())
+}
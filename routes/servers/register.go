@@ -1,6 +1,8 @@
 package servers_routes
 
 import (
+	"log"
+
 	"github.com/Liphium/hytale-matchmaking/service"
 	"github.com/gofiber/fiber/v2"
 )
@@ -8,13 +10,21 @@ import (
 type RegisterServerRequest struct {
 	IP   string `json:"ip"`
 	Port int    `json:"port"`
+
+	Region string   `json:"region,omitempty"` // Where the server is hosted (e.g. eu), used to send players to servers close to them
+	Tags   []string `json:"tags,omitempty"`
+
+	Starting bool `json:"starting,omitempty"` // The server has to announce that it's ready before players are sent there
 }
 
 type RegisterServerResponse struct {
-	ID           int    `json:"id"`
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	UUID         string `json:"uuid"`
+	ID          int    `json:"id"`
+	AccessToken string `json:"access_token"` // The refresh token stays with the matchmaker, it refreshes the token (see access_token_rotated)
+	UUID        string `json:"uuid"`
+	Secret      string `json:"secret"`   // Has to be sent as the Credential header for all other requests about the server
+	Audience    string `json:"audience"` // Join tickets for players of the server have this as their audience (aud)
+
+	Session *service.GameSession `json:"session,omitempty"` // Not set when the session couldn't be created
 }
 
 // Endpoint: /api/servers/register
@@ -27,17 +37,38 @@ func registerServer(c *fiber.Ctx) error {
 	// Find a valid token
 	token, ok := service.GetFreeToken()
 	if !ok {
+		service.ReportRegistrationRefused(req.IP, req.Port)
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 
+	// Let people know in case the pool is about to run out
+	service.CheckTokenPool()
+
 	token.Mutex.Lock()
-	defer token.Mutex.Unlock()
-
-	service.CreateServer(token.Id, req.IP, req.Port)
-	return c.JSON(RegisterServerResponse{
-		ID:           token.Id,
-		AccessToken:  token.Token.AccessToken,
-		RefreshToken: token.Token.RefreshToken,
-		UUID:         token.Token.UUID,
-	})
+	res := RegisterServerResponse{
+		ID:          token.Id,
+		AccessToken: token.Token.AccessToken,
+		UUID:        token.Token.UUID,
+	}
+	token.Mutex.Unlock()
+
+	if !service.CreateServerInRegion(res.ID, req.IP, req.Port, req.Region, req.Tags) {
+		service.MarkTokenAsUnused(res.ID)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	if req.Starting {
+		service.MarkServerStarting(res.ID)
+	}
+	res.Secret, _ = service.IssueServerSecret(res.ID)
+	res.Audience, _ = service.GetTicketAudience(res.ID)
+
+	// Create the game session for the server (it can still create one itself in case this fails)
+	session, err := service.GetGameSession(res.ID)
+	if err != nil {
+		log.Println("Couldn't create game session for server", res.ID, ":", err)
+	} else {
+		res.Session = &session
+	}
+
+	return c.JSON(res)
 }
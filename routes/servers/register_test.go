@@ -0,0 +1,81 @@
+package servers_routes_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	servers_routes "github.com/Liphium/hytale-matchmaking/routes/servers"
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/Liphium/hytale-matchmaking/util"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"resty.dev/v3"
+)
+
+func TestRegisterWithGameSession(t *testing.T) {
+	service.ResetAll()
+	t.Setenv("TOKEN_FILE_LOCATION", t.TempDir())
+
+	// Local stand-in for the Hytale session endpoint
+	sessions := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(service.GameSessionResponse{
+			SessionToken:  "session",
+			IdentityToken: "identity",
+			ExpiresAt:     time.Now().Add(time.Hour),
+		})
+	}))
+	defer sessions.Close()
+	t.Setenv("HYTALE_SESSION_URL", sessions.URL)
+
+	service.AddToken(service.Token{
+		AccessToken: "access",
+		UUID:        "profile",
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+
+	client := resty.New()
+	defer client.Close()
+
+	var id int
+	t.Run("registering returns a game session", func(t *testing.T) {
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			SetBody(servers_routes.RegisterServerRequest{
+				IP:   "localhost",
+				Port: 3000,
+			}).
+			Post(util.DefaultPath("/api/servers/register"))
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+
+		var r servers_routes.RegisterServerResponse
+		testing_util.Unmarshal(t, res.Bytes(), &r)
+		assert.Equal(t, "access", r.AccessToken)
+		if assert.NotNil(t, r.Session) {
+			assert.Equal(t, "session", r.Session.SessionToken)
+			assert.Equal(t, "identity", r.Session.IdentityToken)
+		}
+		id = r.ID
+	})
+
+	t.Run("renewing returns the same session", func(t *testing.T) {
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			SetBody(servers_routes.RenewServerRequest{
+				ID: id,
+			}).
+			Post(util.DefaultPath("/api/servers/renew"))
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusOK, res.StatusCode())
+
+		var r servers_routes.RenewServerResponse
+		testing_util.Unmarshal(t, res.Bytes(), &r)
+		if assert.NotNil(t, r.Session) {
+			assert.Equal(t, "session", r.Session.SessionToken)
+		}
+	})
+}
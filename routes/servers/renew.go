@@ -1,12 +1,20 @@
 package servers_routes
 
 import (
+	"log"
+
 	"github.com/Liphium/hytale-matchmaking/service"
 	"github.com/gofiber/fiber/v2"
 )
 
 type RenewServerRequest struct {
-	ID int `json:"id"`
+	ID     int                   `json:"id"`
+	Roster []service.MatchRoster `json:"roster,omitempty"` // Who is actually connected to the server's matches (the matchmaker is corrected with it)
+}
+
+type RenewServerResponse struct {
+	Session     *service.GameSession       `json:"session,omitempty"`     // Always the most recent session, not set when it couldn't be created
+	Corrections *service.RosterCorrections `json:"corrections,omitempty"` // Only set when a roster was sent
 }
 
 // Endpoint: /api/servers/renew
@@ -16,6 +24,28 @@ func renewServer(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusBadRequest)
 	}
 
+	if !service.OwnsServer(c, req.ID) {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
 	service.RefreshServer(req.ID)
-	return c.SendStatus(fiber.StatusOK)
+
+	// Fix everything the matchmaker got wrong about who is connected
+	var res RenewServerResponse
+	if req.Roster != nil {
+		corrections := service.ReconcileRoster(req.ID, req.Roster)
+		res.Corrections = &corrections
+	}
+
+	// Hand out the current game session (a new one is created in case the old one expires soon)
+	if _, _, ok := service.GetServerDetails(req.ID); ok {
+		session, err := service.GetGameSession(req.ID)
+		if err != nil {
+			log.Println("Couldn't get game session for server", req.ID, ":", err)
+		} else {
+			res.Session = &session
+		}
+	}
+
+	return c.JSON(res)
 }
@@ -7,10 +7,19 @@ import (
 
 func SetupRoutes(router fiber.Router) {
 
-	// Require the credential as a Header
-	router.Use(service.DefaultAuthMiddleware())
+	// The key for verifying join tickets isn't a secret
+	router.Get("/jwks", getJWKS)
+
+	// Servers register with the registration credential and get their own secret for everything else
+	router.Post("/register", service.AuthMiddleware(service.RoleRegistration), registerServer)
+
+	// Require the secret of the server as a Header (every route checks that it's actually for that server)
+	router.Use(service.AuthMiddleware(service.RoleGameServer))
 
 	router.Post("/set_access_token", setToken)
+	router.Post("/access_token", getAccessToken)
 	router.Post("/renew", renewServer)
-	router.Post("/register", registerServer)
+	router.Post("/should_start_match", shouldStartMatch)
+	router.Post("/set_state", setServerState)
+	router.Get("/events", streamEvents)
 }
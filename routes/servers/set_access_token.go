@@ -1,6 +1,8 @@
 package servers_routes
 
 import (
+	"time"
+
 	"github.com/Liphium/hytale-matchmaking/service"
 	"github.com/gofiber/fiber/v2"
 )
@@ -8,6 +10,7 @@ import (
 type SetTokenRequest struct {
 	Id          int    `json:"id"`
 	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in,omitempty"` // Seconds until the access token expires (service.DefaultTokenLifetime when not set)
 }
 
 // Endpoint: /api/servers/set_access_token
@@ -17,6 +20,14 @@ func setToken(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusBadRequest)
 	}
 
-	service.ReplaceAccessToken(req.Id, req.AccessToken)
+	if !service.OwnsServer(c, req.Id) {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	expiresAt := time.Now().Add(service.DefaultTokenLifetime)
+	if req.ExpiresIn > 0 {
+		expiresAt = time.Now().Add(time.Duration(req.ExpiresIn) * time.Second)
+	}
+	service.ReplaceAccessToken(req.Id, req.AccessToken, expiresAt)
 	return c.SendStatus(fiber.StatusOK)
 }
@@ -0,0 +1,45 @@
+package servers_routes
+
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Servers announce where they are in their lifecycle using this endpoint. A server that is draining (or stopping) keeps its running
+// matches, but no new players are sent there. Once the last match ended, the server is stopped and removed by the matchmaker.
+
+type SetServerStateRequest struct {
+	ID    int    `json:"id"`
+	State string `json:"state"` // ready, draining or stopping (stopped is set by the matchmaker)
+}
+
+type SetServerStateResponse struct {
+	State string `json:"state"` // The state the server is in now (stopped in case it didn't have any matches left)
+}
+
+// Endpoint: /api/servers/set_state
+func setServerState(c *fiber.Ctx) error {
+	var req SetServerStateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	if !service.OwnsServer(c, req.ID) {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	if _, ok := service.GetServerState(req.ID); !ok {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	if !service.SetServerState(req.ID, req.State) {
+		return c.SendStatus(fiber.StatusConflict)
+	}
+
+	state, ok := service.GetServerState(req.ID)
+	if !ok {
+		state = service.ServerStateStopped // Already removed since nothing was running anymore
+	}
+	return c.JSON(SetServerStateResponse{
+		State: state,
+	})
+}
@@ -0,0 +1,80 @@
+package servers_routes_test
+
+import (
+	"testing"
+
+	servers_routes "github.com/Liphium/hytale-matchmaking/routes/servers"
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/Liphium/hytale-matchmaking/util"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"resty.dev/v3"
+)
+
+func TestSetServerState(t *testing.T) {
+	service.ResetAll()
+
+	// One server that's still starting and hosts a match already
+	assert.True(t, service.CreateServer(1, "localhost", 3000))
+	assert.True(t, service.MarkServerStarting(1))
+	assert.True(t, service.AddMatch(1, service.MatchCreate{
+		ID:   1,
+		Game: "battle",
+	}, []string{"test"}))
+
+	setState := func(t *testing.T, id int, state string) (int, servers_routes.SetServerStateResponse) {
+		client := resty.New()
+		defer client.Close()
+
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			SetBody(servers_routes.SetServerStateRequest{
+				ID:    id,
+				State: state,
+			}).
+			Post(util.DefaultPath("/api/servers/set_state"))
+		assert.Nil(t, err)
+
+		var r servers_routes.SetServerStateResponse
+		if res.StatusCode() == fiber.StatusOK {
+			testing_util.Unmarshal(t, res.Bytes(), &r)
+		}
+		return res.StatusCode(), r
+	}
+
+	t.Run("starting server becomes ready", func(t *testing.T) {
+		status, r := setState(t, 1, service.ServerStateReady)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, service.ServerStateReady, r.State)
+	})
+
+	t.Run("invalid transitions are rejected", func(t *testing.T) {
+		status, _ := setState(t, 1, service.ServerStateStarting)
+		assert.Equal(t, fiber.StatusConflict, status)
+		status, _ = setState(t, 1, "exploded")
+		assert.Equal(t, fiber.StatusConflict, status)
+	})
+
+	t.Run("server with a match keeps draining", func(t *testing.T) {
+		status, r := setState(t, 1, service.ServerStateDraining)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, service.ServerStateDraining, r.State)
+	})
+
+	t.Run("server without matches stops right away", func(t *testing.T) {
+		assert.True(t, service.SetMatchState(1, 1, service.MatchStateEnd))
+
+		_, ok := service.GetServerState(1)
+		assert.False(t, ok)
+		status, _ := setState(t, 1, service.ServerStateStopping)
+		assert.Equal(t, fiber.StatusNotFound, status)
+	})
+
+	t.Run("idle server stops when announcing it", func(t *testing.T) {
+		assert.True(t, service.CreateServer(2, "localhost", 3001))
+		status, r := setState(t, 2, service.ServerStateStopping)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, service.ServerStateStopped, r.State)
+	})
+}
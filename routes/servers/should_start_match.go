@@ -1,5 +1,39 @@
 package servers_routes
 
-// This endpoint serves as a reference for the server to know if it should start a new match. Our match-making service should now know how many servers should be kept alive at a time. If we notice that currently more game servers than needed are hosting matches, we want to shut one down so others can be used.
-// In this case, in this endpoint, we should choose the server with the lowest match count and tell it to never start a new match using this endpoint. This system should be able to do this dynamically based on the current conditions and also based on the fact, that we may need to shut down multiple servers.
-// When a game server gets told that it can't start new matches and doesn't currently host any, it should shut itself down.
+import (
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// This endpoint serves as a reference for the server to know if it should start a new match. Our match-making service knows how many servers should be kept alive at a time. If we notice that currently more game servers than needed are hosting matches, we shut one down so others can be used.
+// In this case, the capacity planner chooses the servers with the lowest match count and tells them to never start a new match using this endpoint. This is done dynamically based on the current conditions and may drain multiple servers at once (or bring them back when they are needed again).
+// When a game server gets told that it can't start new matches and doesn't currently host any, it should shut itself down (announcing that it's stopping first, see set_state.go).
+
+type ShouldStartMatchRequest struct {
+	ID int `json:"id"`
+}
+
+type ShouldStartMatchResponse struct {
+	Start bool `json:"start"`
+}
+
+// Endpoint: /api/servers/should_start_match
+func shouldStartMatch(c *fiber.Ctx) error {
+	var req ShouldStartMatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	if !service.OwnsServer(c, req.ID) {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	start, ok := service.ShouldStartMatch(req.ID)
+	if !ok {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	return c.JSON(ShouldStartMatchResponse{
+		Start: start,
+	})
+}
@@ -0,0 +1,61 @@
+package servers_routes_test
+
+import (
+	"testing"
+
+	servers_routes "github.com/Liphium/hytale-matchmaking/routes/servers"
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/Liphium/hytale-matchmaking/util"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"resty.dev/v3"
+)
+
+func TestShouldStartMatch(t *testing.T) {
+	service.ResetAll()
+
+	// One server with a match and one that just registered
+	assert.True(t, service.CreateServer(1, "localhost", 3000))
+	assert.True(t, service.CreateServer(2, "localhost", 3001))
+	assert.True(t, service.AddMatch(1, service.MatchCreate{
+		ID:   1,
+		Game: "battle",
+	}, []string{"test"}))
+
+	shouldStart := func(t *testing.T, id int) (int, servers_routes.ShouldStartMatchResponse) {
+		client := resty.New()
+		defer client.Close()
+
+		res, err := client.R().
+			SetHeaders(util.CredentialHeaders()).
+			SetBody(servers_routes.ShouldStartMatchRequest{
+				ID: id,
+			}).
+			Post(util.DefaultPath("/api/servers/should_start_match"))
+		assert.Nil(t, err)
+
+		var r servers_routes.ShouldStartMatchResponse
+		if res.StatusCode() == fiber.StatusOK {
+			testing_util.Unmarshal(t, res.Bytes(), &r)
+		}
+		return res.StatusCode(), r
+	}
+
+	t.Run("server hosting a match can start more", func(t *testing.T) {
+		status, r := shouldStart(t, 1)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.True(t, r.Start)
+	})
+
+	t.Run("freshly registered server can start its first match", func(t *testing.T) {
+		status, r := shouldStart(t, 2)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.True(t, r.Start)
+	})
+
+	t.Run("unknown server is rejected", func(t *testing.T) {
+		status, _ := shouldStart(t, 67)
+		assert.Equal(t, fiber.StatusBadRequest, status)
+	})
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Everything about a server for the admin API
+type ServerView struct {
+	ID       int          `json:"id"`
+	IP       string       `json:"ip"`
+	Port     int          `json:"port"`
+	Region   string       `json:"region,omitempty"`
+	Tags     []string     `json:"tags,omitempty"`
+	State    string       `json:"state"`
+	RenewAge float64      `json:"renew_age"` // Seconds since the server renewed for the last time
+	TTLLeft  float64      `json:"ttl_left"`  // Seconds until the server is evicted without renewing
+	Matches  []MatchView  `json:"matches"`
+	Players  []PlayerView `json:"players"`
+}
+
+type MatchView struct {
+	ID         int      `json:"id"`
+	Server     int      `json:"server"`
+	Game       string   `json:"game"`
+	State      string   `json:"state"`
+	Players    []string `json:"players"`
+	Spectators []string `json:"spectators"`
+	FreeTokens int      `json:"free_tokens"` // Tokens that haven't been handed out yet (the tokens themselves aren't shown)
+	MinPlayers int      `json:"min_players,omitempty"`
+	MaxPlayers int      `json:"max_players"`
+	Fill       float64  `json:"fill"`
+	JoinCode   string   `json:"join_code,omitempty"` // Only set for private matches
+}
+
+type PlayerView struct {
+	Account   string `json:"account"`
+	Server    int    `json:"server"`
+	Match     int    `json:"match"`
+	Confirmed bool   `json:"confirmed"`
+	Spectator bool   `json:"spectator,omitempty"`
+}
+
+// Where a player currently is (either on a server or in a queue)
+type PlayerLookup struct {
+	Account string      `json:"account"`
+	Player  *PlayerView `json:"player,omitempty"` // Only set when the player has a slot on a server
+	Queue   *QueueView  `json:"queue,omitempty"`  // Only set when the player is in a queue
+}
+
+type QueueView struct {
+	Game     string   `json:"game"`
+	Accounts []string `json:"accounts"` // Everyone queued together with the player
+	Position int      `json:"position"`
+	Assigned bool     `json:"assigned"`
+}
+
+// Everyone waiting in the queue of a game
+type QueueSummary struct {
+	Game    string `json:"game"`
+	Entries int    `json:"entries"` // Solo players and parties waiting
+	Players int    `json:"players"`
+}
+
+// A token from the pool without any of the secrets
+type TokenView struct {
+	ID               int        `json:"id"`
+	Account          string     `json:"account"`
+	UUID             string     `json:"uuid"`
+	Used             bool       `json:"used"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	CanRefresh       bool       `json:"can_refresh"`
+	SessionExpiresAt *time.Time `json:"session_expires_at,omitempty"`
+}
+
+// Get all registered servers (sorted by id)
+func ListServers() []ServerView {
+	servers := []ServerView{}
+	rangeServers(func(id int, server *ServerInfo) bool {
+		servers = append(servers, viewServer(server))
+		return true
+	})
+	slices.SortFunc(servers, func(a, b ServerView) int {
+		return a.ID - b.ID
+	})
+	return servers
+}
+
+// Get a server with all of its matches and players
+func GetServerView(id int) (ServerView, bool) {
+	server, ok := serverCache.Get(id)
+	if !ok {
+		return ServerView{}, false
+	}
+	return viewServer(server), true
+}
+
+// Get all matches, optionally only the ones of a game or in a state (empty to not filter)
+func ListMatches(game string, state string) []MatchView {
+	matches := []MatchView{}
+	rangeServers(func(id int, server *ServerInfo) bool {
+		server.Matches.Range(func(key, value any) bool {
+			view := viewMatch(value.(*Match))
+			if (game == "" || view.Game == game) && (state == "" || view.State == state) {
+				matches = append(matches, view)
+			}
+			return true
+		})
+		return true
+	})
+	slices.SortFunc(matches, func(a, b MatchView) int {
+		if a.Server != b.Server {
+			return a.Server - b.Server
+		}
+		return a.ID - b.ID
+	})
+	return matches
+}
+
+// Get how many players are waiting for every game (sorted by game)
+func ListQueues() []QueueSummary {
+	queues := []QueueSummary{}
+	gameCache.Range(func(key, value any) bool {
+		mr := value.(*MatchRegistry)
+
+		mr.queueMutex.Lock()
+		defer mr.queueMutex.Unlock()
+		summary := QueueSummary{
+			Game:    mr.Game,
+			Entries: len(mr.queue),
+		}
+		for _, entry := range mr.queue {
+			summary.Players += len(entry.Accounts)
+		}
+		queues = append(queues, summary)
+		return true
+	})
+	slices.SortFunc(queues, func(a, b QueueSummary) int {
+		return strings.Compare(a.Game, b.Game)
+	})
+	return queues
+}
+
+// Find out where a player is (false when they're neither on a server nor queued)
+func LookupPlayer(account string) (PlayerLookup, bool) {
+	lookup := PlayerLookup{
+		Account: account,
+	}
+
+	if player, ok := getPlayer(account); ok {
+		view := viewPlayer(player)
+		lookup.Player = &view
+	}
+
+	if entry, ok := queueCache.Get(account); ok {
+		status, ok := GetQueueStatus(account)
+		if ok {
+			entry.Mutex.RLock()
+			lookup.Queue = &QueueView{
+				Game:     entry.Game,
+				Accounts: slices.Clone(entry.Accounts),
+				Position: status.Position,
+				Assigned: status.Assigned,
+			}
+			entry.Mutex.RUnlock()
+		}
+	}
+
+	return lookup, lookup.Player != nil || lookup.Queue != nil
+}
+
+// End a match right away (the server is told about it and all players are removed)
+func EndMatch(server int, matchId int) bool {
+	if _, ok := GetMatchFromServer(server, matchId); !ok {
+		return false
+	}
+
+	// Told before the match actually ends, the server might be stopped right after
+	publishEvent(server, Event{
+		Type: EventMatchEnded,
+		Data: MatchEvent{
+			Match: matchId,
+		},
+	})
+	return SetMatchState(server, matchId, MatchStateEnd)
+}
+
+// Remove a player from their server and any queue they are in (false if they are nowhere)
+func KickPlayer(account string) bool {
+	queued := CancelQueue(account)
+
+	cached, ok := PlayerCache.Get(account)
+	if !ok {
+		return queued
+	}
+	DeletePlayer(account, &cached)
+	return true
+}
+
+// Evict a server as if it stopped renewing (matches in progress are kept until it comes back, evicting it again removes everything)
+func EvictServer(id int) bool {
+	server, ok := serverCache.Get(id)
+	if !ok {
+		return false
+	}
+	evictServer(server)
+	return true
+}
+
+// Get all tokens in the pool (sorted by id)
+func ListTokens() []TokenView {
+	tokens := []TokenView{}
+	tokensMap.Range(func(key, value any) bool {
+		info := value.(*TokenInfo)
+
+		info.Mutex.Lock()
+		defer info.Mutex.Unlock()
+		view := TokenView{
+			ID:         info.Id,
+			Account:    info.Token.Account,
+			UUID:       info.Token.UUID,
+			Used:       info.Used,
+			ExpiresAt:  info.Token.ExpiresAt,
+			CanRefresh: info.Token.RefreshToken != "",
+		}
+		if info.Session != nil {
+			expiresAt := info.Session.ExpiresAt
+			view.SessionExpiresAt = &expiresAt
+		}
+		tokens = append(tokens, view)
+		return true
+	})
+	slices.SortFunc(tokens, func(a, b TokenView) int {
+		return a.ID - b.ID
+	})
+	return tokens
+}
+
+// Helper function for turning a server into its view
+func viewServer(server *ServerInfo) ServerView {
+	server.Mutex.RLock()
+	view := ServerView{
+		ID:       server.TokenId,
+		IP:       server.IP,
+		Port:     server.Port,
+		Region:   server.Region,
+		Tags:     slices.Clone(server.Tags),
+		State:    server.State,
+		RenewAge: time.Since(server.LastRenew).Seconds(),
+		Matches:  []MatchView{},
+		Players:  []PlayerView{},
+	}
+	server.Mutex.RUnlock()
+
+	if ttl, ok := ServerTTLLeft(view.ID); ok {
+		view.TTLLeft = ttl.Seconds()
+	}
+
+	server.Matches.Range(func(key, value any) bool {
+		view.Matches = append(view.Matches, viewMatch(value.(*Match)))
+		return true
+	})
+	slices.SortFunc(view.Matches, func(a, b MatchView) int {
+		return a.ID - b.ID
+	})
+
+	server.Players.Range(func(key, value any) bool {
+		view.Players = append(view.Players, viewPlayer(value.(*PlayerInfo)))
+		return true
+	})
+	slices.SortFunc(view.Players, func(a, b PlayerView) int {
+		return strings.Compare(a.Account, b.Account)
+	})
+	return view
+}
+
+// Helper function for turning a match into its view
+func viewMatch(match *Match) MatchView {
+	match.Mutex.RLock()
+	defer match.Mutex.RUnlock()
+
+	return MatchView{
+		ID:         match.ID,
+		Server:     match.Server,
+		Game:       match.Game,
+		State:      match.State,
+		Players:    slices.Clone(match.Players),
+		Spectators: slices.Clone(match.Spectators),
+		FreeTokens: len(match.TokenStore),
+		MinPlayers: match.MinPlayers,
+		MaxPlayers: match.capacityNoMutex(),
+		Fill:       match.fillNoMutex(),
+		JoinCode:   match.JoinCode,
+	}
+}
+
+// Helper function for turning a player into its view
+func viewPlayer(player *PlayerInfo) PlayerView {
+	player.Mutex.RLock()
+	defer player.Mutex.RUnlock()
+
+	return PlayerView{
+		Account:   player.Account,
+		Server:    player.Server,
+		Match:     player.Match,
+		Confirmed: player.Confirmed,
+		Spectator: player.Spectator,
+	}
+}
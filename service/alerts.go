@@ -0,0 +1,249 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/util"
+)
+
+// Types of alerts that can be sent to the people running the network
+const (
+	AlertLowTokens           = "low_tokens"           // Less than the configured share of tokens is free
+	AlertNoTokens            = "no_tokens"            // Every token is used by a server
+	AlertRegistrationRefused = "registration_refused" // A server couldn't register because there was no free token
+)
+
+const (
+	DefaultLowTokenThreshold = 0.2              // Share of free tokens below which the low tokens alert fires
+	DefaultAlertCooldown     = 30 * time.Minute // The same alert isn't sent again within this time
+	AlertCheckInterval       = 30 * time.Second // How often the token pool is checked
+	DefaultSMTPPort          = 587
+)
+
+type Alert struct {
+	Type    string    `json:"type"`
+	Message string    `json:"message"`
+	Free    int       `json:"free"`  // Free tokens when the alert fired
+	Total   int       `json:"total"` // All tokens in the pool
+	Time    time.Time `json:"time"`
+}
+
+// Something that can deliver alerts (e.g. mail or a webhook)
+type AlertNotifier interface {
+	Notify(alert Alert) error
+}
+
+type AlertConfig struct {
+	LowTokenThreshold float64
+	Cooldown          time.Duration
+	Notifiers         []AlertNotifier
+}
+
+var alertMutex = &sync.Mutex{}
+var alertConfig = AlertConfig{
+	LowTokenThreshold: DefaultLowTokenThreshold,
+	Cooldown:          DefaultAlertCooldown,
+}
+var alertsSent = map[string]time.Time{} // Alert type -> when it was last sent
+
+// Configure alerting from the environment and start watching the token pool (does nothing when no notifier is configured)
+func SetupAlerts() {
+	config := AlertConfig{
+		LowTokenThreshold: DefaultLowTokenThreshold,
+		Cooldown:          DefaultAlertCooldown,
+		Notifiers:         []AlertNotifier{},
+	}
+
+	if value := os.Getenv("ALERT_LOW_TOKEN_THRESHOLD"); value != "" {
+		threshold, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			log.Fatalln("Invalid ALERT_LOW_TOKEN_THRESHOLD:", err)
+		}
+		config.LowTokenThreshold = threshold
+	}
+	if value := os.Getenv("ALERT_COOLDOWN"); value != "" {
+		cooldown, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalln("Invalid ALERT_COOLDOWN:", err)
+		}
+		config.Cooldown = cooldown
+	}
+
+	for _, url := range splitList(os.Getenv("ALERT_WEBHOOK_URLS")) {
+		config.Notifiers = append(config.Notifiers, &WebhookNotifier{
+			URL: url,
+		})
+	}
+
+	if host := os.Getenv("ALERT_SMTP_HOST"); host != "" {
+		port := DefaultSMTPPort
+		if value := os.Getenv("ALERT_SMTP_PORT"); value != "" {
+			var err error
+			port, err = strconv.Atoi(value)
+			if err != nil {
+				log.Fatalln("Invalid ALERT_SMTP_PORT:", err)
+			}
+		}
+
+		config.Notifiers = append(config.Notifiers, &MailNotifier{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("ALERT_SMTP_USERNAME"),
+			Password: os.Getenv("ALERT_SMTP_PASSWORD"),
+			From:     os.Getenv("ALERT_MAIL_FROM"),
+			To:       splitList(os.Getenv("ALERT_MAIL_TO")),
+		})
+	}
+
+	ConfigureAlerts(config)
+	if len(config.Notifiers) == 0 {
+		log.Println("No alert notifiers configured, alerting is disabled.")
+		return
+	}
+
+	go func() {
+		for {
+			CheckTokenPool()
+			time.Sleep(AlertCheckInterval)
+		}
+	}()
+}
+
+// Replace the alert configuration (also forgets when alerts were last sent)
+func ConfigureAlerts(config AlertConfig) {
+	alertMutex.Lock()
+	defer alertMutex.Unlock()
+	alertConfig = config
+	alertsSent = map[string]time.Time{}
+}
+
+// Get how many tokens are free and how many there are in total
+func TokenPoolStats() (free int, total int) {
+	tokensMap.Range(func(key, value any) bool {
+		info := value.(*TokenInfo)
+		info.Mutex.Lock()
+		defer info.Mutex.Unlock()
+
+		total++
+		if !info.Used {
+			free++
+		}
+		return true
+	})
+	return free, total
+}
+
+// Check the token pool and send an alert in case it's running low (returns the type of alert that fired, empty if none)
+func CheckTokenPool() string {
+	free, total := TokenPoolStats()
+
+	alertMutex.Lock()
+	threshold := alertConfig.LowTokenThreshold
+	alertMutex.Unlock()
+
+	switch {
+	case free == 0:
+		sendAlert(AlertNoTokens, fmt.Sprintf("All %d tokens are in use, new servers can't register.", total), free, total)
+		return AlertNoTokens
+	case float64(free) < float64(total)*threshold:
+		sendAlert(AlertLowTokens, fmt.Sprintf("Only %d of %d tokens are still free.", free, total), free, total)
+		return AlertLowTokens
+	}
+	return ""
+}
+
+// Send an alert because a server couldn't get a token
+func ReportRegistrationRefused(ip string, port int) {
+	free, total := TokenPoolStats()
+	sendAlert(AlertRegistrationRefused, fmt.Sprintf("Server %s:%d couldn't register because there is no free token.", ip, port), free, total)
+}
+
+// Helper function for sending an alert to all notifiers (returns false when it has been sent too recently)
+func sendAlert(alertType string, message string, free int, total int) bool {
+	alertMutex.Lock()
+	if last, ok := alertsSent[alertType]; ok && time.Since(last) < alertConfig.Cooldown {
+		alertMutex.Unlock()
+		return false
+	}
+	alertsSent[alertType] = time.Now()
+	notifiers := alertConfig.Notifiers
+	alertMutex.Unlock()
+
+	alert := Alert{
+		Type:    alertType,
+		Message: message,
+		Free:    free,
+		Total:   total,
+		Time:    time.Now(),
+	}
+	log.Println("Alert:", message)
+
+	// Deliver in the background so nothing has to wait for slow mail servers
+	for _, notifier := range notifiers {
+		go func() {
+			if err := notifier.Notify(alert); err != nil {
+				log.Println("Couldn't deliver alert", alertType, ":", err)
+			}
+		}()
+	}
+	return true
+}
+
+// Sends alerts as JSON to any URL
+type WebhookNotifier struct {
+	URL string
+}
+
+func (wn *WebhookNotifier) Notify(alert Alert) error {
+	return util.PostWithoutResponse(wn.URL, alert, util.Headers{})
+}
+
+// Sends alerts as mail using SMTP (authentication is only used when a username is set)
+type MailNotifier struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (mn *MailNotifier) Notify(alert Alert) error {
+	var auth smtp.Auth
+	if mn.Username != "" {
+		auth = smtp.PlainAuth("", mn.Username, mn.Password, mn.Host)
+	}
+
+	message := strings.Join([]string{
+		"From: " + mn.From,
+		"To: " + strings.Join(mn.To, ", "),
+		"Subject: [Hytale Matchmaking] " + alert.Type,
+		"Date: " + alert.Time.Format(time.RFC1123Z),
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		alert.Message,
+		"",
+		fmt.Sprintf("Free tokens: %d/%d", alert.Free, alert.Total),
+	}, "\r\n")
+
+	return smtp.SendMail(net.JoinHostPort(mn.Host, strconv.Itoa(mn.Port)), auth, mn.From, mn.To, []byte(message))
+}
+
+// Helper function for splitting a comma separated list from the environment
+func splitList(value string) []string {
+	list := []string{}
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
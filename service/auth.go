@@ -1,18 +1,120 @@
 package service
 
 import (
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
 	"github.com/Liphium/hytale-matchmaking/util"
 	"github.com/gofiber/fiber/v2"
 )
 
-func DefaultAuthMiddleware() func(c *fiber.Ctx) error {
+// Length of the random part of the secrets servers get when registering
+const ServerSecretLength = 48
+
+// Roles a caller of the API can have
+const (
+	RoleAdmin        = "admin"        // Can do everything (uses CREDENTIAL)
+	RoleLobby        = "lobby"        // Can queue players, but doesn't own any server (uses LOBBY_CREDENTIAL)
+	RoleGameServer   = "game_server"  // Can only manage its own server (uses the secret it got when registering)
+	RoleRegistration = "registration" // Can only register new servers (uses REGISTRATION_CREDENTIAL)
+)
+
+// Who is calling the API (stored in the locals of every authenticated request)
+type Caller struct {
+	Role   string
+	Server int // Only set for game servers
+}
+
+// Get the credential game servers use for registering (falls back to the admin credential)
+func GetRegistrationCredential() string {
+	if credential := os.Getenv("REGISTRATION_CREDENTIAL"); credential != "" {
+		return credential
+	}
+	return util.GetCredential()
+}
+
+// Get the credential for lobbies (empty when lobbies have to use the admin credential)
+func GetLobbyCredential() string {
+	return os.Getenv("LOBBY_CREDENTIAL")
+}
+
+// Create a new secret for a server (the id is part of it so the server can be found again)
+func newServerSecret(id int) string {
+	return fmt.Sprintf("%d.%s", id, util.GenerateToken(ServerSecretLength))
+}
+
+// Figure out who's behind a credential (false when it isn't valid)
+func resolveCaller(credential string) (Caller, bool) {
+	if credential == "" {
+		return Caller{}, false
+	}
+	if equalSecrets(credential, util.GetCredential()) {
+		return Caller{Role: RoleAdmin}, true
+	}
+	if lobby := GetLobbyCredential(); lobby != "" && equalSecrets(credential, lobby) {
+		return Caller{Role: RoleLobby}, true
+	}
+	if equalSecrets(credential, GetRegistrationCredential()) {
+		return Caller{Role: RoleRegistration}, true
+	}
+
+	// Check if it's the secret of a server
+	prefix, _, found := strings.Cut(credential, ".")
+	if !found {
+		return Caller{}, false
+	}
+	id, err := strconv.Atoi(prefix)
+	if err != nil {
+		return Caller{}, false
+	}
+	server, ok := serverCache.Get(id)
+	if !ok {
+		return Caller{}, false
+	}
+
+	server.Mutex.RLock()
+	secret := server.Secret
+	server.Mutex.RUnlock()
+	if secret == "" || !equalSecrets(credential, secret) {
+		return Caller{}, false
+	}
+	return Caller{Role: RoleGameServer, Server: id}, true
+}
+
+// Helper function for comparing secrets without leaking how much of them matched
+func equalSecrets(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// Only let callers with one of the roles through (admins are always allowed)
+func AuthMiddleware(roles ...string) func(c *fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
 
-		cred := c.Get("Credential", "-")
-		if cred != util.GetCredential() {
+		caller, ok := resolveCaller(c.Get("Credential"))
+		if !ok {
 			return c.SendStatus(fiber.StatusUnauthorized)
 		}
+		if caller.Role != RoleAdmin && !slices.Contains(roles, caller.Role) {
+			return c.SendStatus(fiber.StatusForbidden)
+		}
 
+		c.Locals("caller", caller)
 		return c.Next()
 	}
 }
+
+// Get the caller of an authenticated request
+func GetCaller(c *fiber.Ctx) Caller {
+	caller, _ := c.Locals("caller").(Caller)
+	return caller
+}
+
+// Check if the caller is allowed to act for a server (only the server itself and admins are)
+func OwnsServer(c *fiber.Ctx, server int) bool {
+	caller := GetCaller(c)
+	return caller.Role == RoleAdmin || (caller.Role == RoleGameServer && caller.Server == server)
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	MinimumServers     = 1    // Amount of servers that should always be kept alive
+	CapacityHeadroom   = 0.25 // Share of the current player count that should be kept available as free slots
+	DefaultServerSlots = 16   // Slots a server is assumed to have when it doesn't host any matches yet
+
+	NewServerGracePeriod = ServerTTL // Servers that registered this recently aren't drained (they didn't have time to advertise a match yet)
+)
+
+type CapacityPlan struct {
+	Demand int   // Slots that are needed (players + headroom)
+	Supply int   // Slots provided by all servers that aren't draining (servers that just registered aren't counted)
+	Drain  []int // Servers that were told to stop starting new matches
+	Revive []int // Servers that were draining, but are needed again
+}
+
+type serverLoad struct {
+	id       int
+	server   *ServerInfo
+	draining bool
+	fresh    bool // Registered within the grace period and doesn't host anything yet
+	matches  int
+	players  int
+	slots    int
+}
+
+// Makes sure only one plan is applied at a time
+var capacityMutex = &sync.Mutex{}
+
+// Returns whether a server is allowed to start a new match (second return is false when the server doesn't exist)
+func ShouldStartMatch(server int) (bool, bool) {
+	PlanCapacity()
+
+	info, ok := serverCache.Get(server)
+	if !ok {
+		return false, false
+	}
+
+	info.Mutex.RLock()
+	defer info.Mutex.RUnlock()
+	return info.State == ServerStateReady && !info.lost, true
+}
+
+// Compute how many slots are needed across the network and drain (or revive) servers accordingly
+func PlanCapacity() CapacityPlan {
+	capacityMutex.Lock()
+	defer capacityMutex.Unlock()
+
+	plan := CapacityPlan{
+		Demand: computeDemand(),
+		Drain:  []int{},
+		Revive: []int{},
+	}
+	loads := collectServerLoads()
+
+	active := []*serverLoad{}
+	draining := []*serverLoad{}
+	for _, load := range loads {
+		switch {
+		case load.fresh:
+			continue // Left alone until it had time to advertise a match
+		case load.draining:
+			draining = append(draining, load)
+		default:
+			active = append(active, load)
+			plan.Supply += load.slots
+		}
+	}
+
+	// Bring back draining servers when there isn't enough capacity anymore (the ones still hosting the most matches first)
+	slices.SortFunc(draining, func(a, b *serverLoad) int {
+		return b.matches - a.matches
+	})
+	for _, load := range draining {
+		if plan.Supply >= plan.Demand {
+			break
+		}
+
+		setDraining(load.server, false)
+		plan.Supply += load.slots
+		plan.Revive = append(plan.Revive, load.id)
+		active = append(active, load)
+	}
+
+	// Drain the servers with the lowest match count as long as there is enough capacity without them
+	slices.SortFunc(active, func(a, b *serverLoad) int {
+		if a.matches != b.matches {
+			return a.matches - b.matches
+		}
+		return a.players - b.players
+	})
+	remaining := len(active)
+	for _, load := range active {
+		if remaining <= MinimumServers {
+			break
+		}
+		if plan.Supply-load.slots < plan.Demand {
+			continue
+		}
+
+		setDraining(load.server, true)
+		plan.Supply -= load.slots
+		plan.Drain = append(plan.Drain, load.id)
+		remaining--
+	}
+
+	return plan
+}
+
+// Get the amount of slots needed by all games combined
+func computeDemand() int {
+	demand := 0
+	gameCache.Range(func(key, value any) bool {
+		registry := value.(*MatchRegistry)
+
+		players := 0
+		registry.Mutex.RLock()
+		for _, match := range registry.available {
+			match.Mutex.RLock()
+			if match.State != MatchStateEnd {
+				players += len(match.Players)
+			}
+			match.Mutex.RUnlock()
+		}
+		registry.Mutex.RUnlock()
+
+		// Players waiting in the queue need a slot too
+		registry.queueMutex.Lock()
+		for _, entry := range registry.queue {
+			players += len(entry.Accounts)
+		}
+		registry.queueMutex.Unlock()
+
+		demand += players + int(math.Ceil(float64(players)*CapacityHeadroom))
+		return true
+	})
+	return demand
+}
+
+// Get the current load of every registered server
+func collectServerLoads() []*serverLoad {
+	loads := []*serverLoad{}
+	withMatches := 0
+	totalSlots := 0
+	rangeServers(func(id int, server *ServerInfo) bool {
+		load := &serverLoad{
+			id:     id,
+			server: server,
+		}
+
+		// Only ready servers and the ones the planner drained itself are part of the plan
+		server.Mutex.RLock()
+		planned := !server.lost && (server.State == ServerStateReady || (server.State == ServerStateDraining && server.plannedDrain))
+		load.draining = server.State == ServerStateDraining
+		registered := server.Registered
+		server.Mutex.RUnlock()
+		if !planned {
+			return true
+		}
+
+		server.Matches.Range(func(key, value any) bool {
+			match := value.(*Match)
+
+			match.Mutex.RLock()
+			defer match.Mutex.RUnlock()
+			if match.State == MatchStateEnd {
+				return true
+			}
+
+			load.matches++
+			load.players += len(match.Players)
+			load.slots += max(match.capacityNoMutex(), len(match.Players))
+			return true
+		})
+
+		load.fresh = load.matches == 0 && time.Since(registered) < NewServerGracePeriod
+		if load.matches > 0 {
+			withMatches++
+			totalSlots += load.slots
+		}
+		loads = append(loads, load)
+		return true
+	})
+
+	// Servers without matches are assumed to be able to host as many players as the average server
+	estimate := DefaultServerSlots
+	if withMatches > 0 {
+		estimate = int(math.Ceil(float64(totalSlots) / float64(withMatches)))
+	}
+	for _, load := range loads {
+		if load.matches == 0 {
+			load.slots = estimate
+		}
+	}
+
+	return loads
+}
+
+func setDraining(server *ServerInfo, draining bool) {
+	server.Mutex.Lock()
+	switch {
+	case draining && server.State == ServerStateReady:
+		server.State, server.plannedDrain = ServerStateDraining, true
+	case !draining && server.State == ServerStateDraining && server.plannedDrain:
+		server.State, server.plannedDrain = ServerStateReady, false
+	default:
+		server.Mutex.Unlock() // The server changed its state since the plan was made
+		return
+	}
+	id := server.TokenId
+	server.Mutex.Unlock()
+
+	if draining {
+		publishEvent(id, Event{Type: EventDrainRequested})
+	} else {
+		publishEvent(id, Event{Type: EventDrainCancelled})
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"slices"
+	"sync"
+)
+
+// Types of events that can be sent to servers
+const (
+	EventPlayerReserved       = "player_reserved"        // A player got a slot in one of the server's matches
+	EventReservationExpired   = "reservation_expired"    // A player didn't join in time and their slot has been freed
+	EventDrainRequested       = "drain_requested"        // The server shouldn't start any new matches anymore
+	EventDrainCancelled       = "drain_cancelled"        // The server is needed again and can start new matches
+	EventAccessTokenRotated   = "access_token_rotated"   // The access token of the server has been replaced (tokens are never sent over the stream, get it from /api/servers/access_token)
+	EventMatchEnded           = "match_ended"            // A match has been ended by the matchmaker
+	EventGameSessionRefreshed = "game_session_refreshed" // The game session of the server has been replaced
+	EventMatchReady           = "match_ready"            // A match has enough players to start
+	EventMatchReadyCancelled  = "match_ready_cancelled"  // Players left and the match doesn't have enough players anymore
+	EventMatchForceStart      = "match_force_start"      // A match has been ready for a while and should start now
+	EventServerStateChanged   = "server_state_changed"   // The server moved into another state (see lifecycle.go)
+)
+
+// Size of the buffer of each subscription (events are dropped when a subscriber can't keep up)
+const EventBufferSize = 64
+
+type Event struct {
+	Type string `json:"type"`
+	Data any    `json:"data,omitempty"`
+}
+
+type PlayerEvent struct {
+	Player string `json:"player"`
+	Match  int    `json:"match"`
+	Token  string `json:"token,omitempty"`
+
+	Spectator bool `json:"spectator,omitempty"`
+	Rejoin    bool `json:"rejoin,omitempty"` // The player was in the match before and is coming back
+}
+
+type MatchEvent struct {
+	Match int `json:"match"`
+}
+
+type eventSubscribers struct {
+	Mutex    *sync.Mutex
+	channels []chan Event
+}
+
+// Server id -> *eventSubscribers
+var eventCache = &sync.Map{}
+
+// Subscribe to all events for a server (call the returned function to unsubscribe)
+func SubscribeToEvents(server int) (<-chan Event, func()) {
+	obj, _ := eventCache.LoadOrStore(server, &eventSubscribers{
+		Mutex:    &sync.Mutex{},
+		channels: []chan Event{},
+	})
+	subs := obj.(*eventSubscribers)
+
+	channel := make(chan Event, EventBufferSize)
+	subs.Mutex.Lock()
+	subs.channels = append(subs.channels, channel)
+	subs.Mutex.Unlock()
+
+	return channel, func() {
+		subs.Mutex.Lock()
+		defer subs.Mutex.Unlock()
+
+		// Only close when it hasn't been closed because of the server disconnecting
+		if slices.Contains(subs.channels, channel) {
+			subs.channels = slices.DeleteFunc(subs.channels, func(c chan Event) bool {
+				return c == channel
+			})
+			close(channel)
+		}
+	}
+}
+
+// Send an event to everyone subscribed to a server (never blocks)
+func publishEvent(server int, event Event) {
+	obj, ok := eventCache.Load(server)
+	if !ok {
+		return
+	}
+	subs := obj.(*eventSubscribers)
+
+	subs.Mutex.Lock()
+	defer subs.Mutex.Unlock()
+	for _, channel := range subs.channels {
+		select {
+		case channel <- event:
+		default:
+		}
+	}
+}
+
+// Close all subscriptions for a server (for when it disconnects)
+func closeEventStreams(server int) {
+	obj, ok := eventCache.LoadAndDelete(server)
+	if !ok {
+		return
+	}
+	subs := obj.(*eventSubscribers)
+
+	subs.Mutex.Lock()
+	defer subs.Mutex.Unlock()
+	for _, channel := range subs.channels {
+		close(channel)
+	}
+	subs.channels = nil
+}
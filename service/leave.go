@@ -0,0 +1,53 @@
+package service
+
+import (
+	"slices"
+)
+
+// Reasons a server can give for a player leaving
+const (
+	LeaveReasonQuit         = "quit"         // The player left on their own
+	LeaveReasonDisconnected = "disconnected" // The connection to the player was lost (they can rejoin, see rejoin.go)
+	LeaveReasonKicked       = "kicked"       // The server removed the player (e.g. for cheating)
+	LeaveReasonMatchEnded   = "match_ended"  // The player was sent away because the match is over
+)
+
+var leaveReasons = []string{LeaveReasonQuit, LeaveReasonDisconnected, LeaveReasonKicked, LeaveReasonMatchEnded}
+
+// A player a server says is gone
+type PlayerLeave struct {
+	Player string `json:"player"`
+	Reason string `json:"reason"`
+	Retire bool   `json:"retire,omitempty"` // Don't give the token back to the match (e.g. when it's already running and the slot shouldn't be filled again)
+}
+
+// Check if a reason for leaving is one the matchmaker knows
+func IsValidLeaveReason(reason string) bool {
+	return slices.Contains(leaveReasons, reason)
+}
+
+// Remove a player that left a server (false when they aren't on that server)
+func LeavePlayer(server int, leave PlayerLeave) bool {
+	cached, ok := PlayerCache.Get(leave.Player)
+	if !ok || cached.Server != server {
+		return false
+	}
+
+	if leave.Reason == LeaveReasonDisconnected {
+		rememberForRejoin(leave.Player)
+	}
+	deletePlayer(leave.Player, &cached, leave.Retire)
+	playerLeaves.WithLabelValues(leave.Reason).Inc()
+	return true
+}
+
+// Remove all players that left a server at once (returns the ones that weren't on the server)
+func LeavePlayers(server int, leaves []PlayerLeave) []string {
+	unknown := []string{}
+	for _, leave := range leaves {
+		if !LeavePlayer(server, leave) {
+			unknown = append(unknown, leave.Player)
+		}
+	}
+	return unknown
+}
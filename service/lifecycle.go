@@ -0,0 +1,186 @@
+package service
+
+import (
+	"log"
+	"slices"
+)
+
+// States a server goes through (servers announce all of them except for stopped, that one is set by the matchmaker)
+const (
+	ServerStateStarting = "starting" // Registered, but can't host matches yet
+	ServerStateReady    = "ready"    // New players can be sent to the server's matches
+	ServerStateDraining = "draining" // Running matches continue, but nobody new is sent to the server
+	ServerStateStopping = "stopping" // Like draining, but the server can't become ready again
+	ServerStateStopped  = "stopped"  // The last match ended and the server has been removed
+)
+
+// Which states a server can announce in which state
+var serverTransitions = map[string][]string{
+	ServerStateStarting: {ServerStateReady, ServerStateStopping},
+	ServerStateReady:    {ServerStateDraining, ServerStateStopping},
+	ServerStateDraining: {ServerStateReady, ServerStateStopping},
+}
+
+type ServerStateEvent struct {
+	State string `json:"state"`
+}
+
+// Move a server into another state (false when the server doesn't exist or can't go there from its current state)
+func SetServerState(id int, state string) bool {
+	server, ok := serverCache.Get(id)
+	if !ok {
+		return false
+	}
+
+	server.Mutex.Lock()
+	if server.State == state && !server.plannedDrain {
+		server.Mutex.Unlock()
+		return true
+	}
+	if server.State != state && !slices.Contains(serverTransitions[server.State], state) {
+		server.Mutex.Unlock()
+		return false
+	}
+	server.State = state
+	server.plannedDrain = false // The server decided itself now, so the planner shouldn't bring it back
+	server.Mutex.Unlock()
+
+	publishEvent(id, Event{
+		Type: EventServerStateChanged,
+		Data: ServerStateEvent{
+			State: state,
+		},
+	})
+	stopServerIfDone(server)
+	return true
+}
+
+// Put a server that just registered into the starting state (it has to tell the matchmaker when it's ready)
+func MarkServerStarting(id int) bool {
+	server, ok := serverCache.Get(id)
+	if !ok {
+		return false
+	}
+
+	server.Mutex.Lock()
+	defer server.Mutex.Unlock()
+	if server.State != ServerStateReady {
+		return false
+	}
+	server.State = ServerStateStarting
+	return true
+}
+
+// Get the state a server is currently in
+func GetServerState(id int) (string, bool) {
+	server, ok := serverCache.Get(id)
+	if !ok {
+		return "", false
+	}
+
+	server.Mutex.RLock()
+	defer server.Mutex.RUnlock()
+	return server.State, true
+}
+
+// Check if players can be sent to matches on a server (the planner only stops servers from starting new matches, so those still count)
+func isServerSelectable(id int) bool {
+	server, ok := serverCache.Get(id)
+	if !ok {
+		return false
+	}
+
+	server.Mutex.RLock()
+	defer server.Mutex.RUnlock()
+	return !server.lost && (server.State == ServerStateReady || (server.State == ServerStateDraining && server.plannedDrain))
+}
+
+// Check if a server is on its way out (drained by the planner doesn't count, those can still come back)
+func (s *ServerInfo) leavingNoMutex() bool {
+	return s.lost || s.State == ServerStateStopping || (s.State == ServerStateDraining && !s.plannedDrain)
+}
+
+// Count the matches on a server that haven't ended yet
+func countLiveMatches(server *ServerInfo) int {
+	live := 0
+	server.Matches.Range(func(key, value any) bool {
+		match := value.(*Match)
+
+		match.Mutex.RLock()
+		defer match.Mutex.RUnlock()
+		if match.State != MatchStateEnd {
+			live++
+		}
+		return true
+	})
+	return live
+}
+
+// Stop a server that is on its way out once its last match ended (it's removed right away so the token is free again)
+func stopServerIfDone(server *ServerInfo) {
+	if countLiveMatches(server) > 0 {
+		return
+	}
+
+	server.Mutex.Lock()
+	if !server.leavingNoMutex() {
+		server.Mutex.Unlock()
+		return
+	}
+	server.State = ServerStateStopped
+	id := server.TokenId
+	server.Mutex.Unlock()
+
+	publishEvent(id, Event{
+		Type: EventServerStateChanged,
+		Data: ServerStateEvent{
+			State: ServerStateStopped,
+		},
+	})
+	removeServer(server)
+}
+
+// Take a server out of the cache and clean up everything on it
+func removeServer(server *ServerInfo) {
+	if current, ok := serverCache.Get(server.TokenId); ok && current == server {
+		serverCache.Del(server.TokenId)
+		serverCache.Wait()
+	}
+	cleanupServer(server)
+}
+
+// Evict a server, matches in progress are kept (with their players) until it renews again or LostServerTimeout is over
+func evictServer(server *ServerInfo) {
+	inProgress := map[int]bool{}
+	server.Matches.Range(func(key, value any) bool {
+		match := value.(*Match)
+
+		match.Mutex.RLock()
+		defer match.Mutex.RUnlock()
+		if match.State == MatchStateFull {
+			inProgress[match.ID] = true
+		}
+		return true
+	})
+
+	// Servers that were already evicted once (or have nothing going on) are removed for real
+	server.Mutex.Lock()
+	keep := len(inProgress) > 0 && !server.lost
+	server.lost = keep
+	id := server.TokenId
+	server.Mutex.Unlock()
+	if !keep || !serverCache.SetWithTTL(id, server, 1, LostServerTimeout) {
+		removeServer(server)
+		return
+	}
+	serverCache.Wait()
+	log.Println("Server", server.IP, "was evicted, keeping", len(inProgress), "matches in progress until it comes back.")
+
+	// Nobody new can join the server anyway, so everything that didn't start yet is ended
+	server.Matches.Range(func(key, value any) bool {
+		if !inProgress[key.(int)] {
+			TransitionMatch(id, key.(int), MatchStateEnd)
+		}
+		return true
+	})
+}
@@ -1,8 +1,11 @@
 package service
 
 import (
+	"errors"
+	"fmt"
 	"slices"
 	"sync"
+	"time"
 )
 
 // States for matches
@@ -13,6 +16,36 @@ const (
 	MatchStateEnd       = "end" // State to mark the match for deletion
 )
 
+// Which states a match can go to from which state (a match that ended can't go anywhere)
+var matchTransitions = map[string][]string{
+	MatchStateAvailable: {MatchStateAccepting, MatchStateFull, MatchStateEnd},
+	MatchStateAccepting: {MatchStateAvailable, MatchStateFull, MatchStateEnd},
+	MatchStateFull:      {MatchStateAccepting, MatchStateEnd},
+}
+
+var (
+	ErrMatchNotFound          = errors.New("match not found")
+	ErrUnknownMatchState      = errors.New("unknown match state")
+	ErrIllegalMatchTransition = errors.New("illegal match state transition")
+)
+
+// Check if a state is one matches can be in
+func IsValidMatchState(state string) bool {
+	_, ok := matchTransitions[state]
+	return ok || state == MatchStateEnd
+}
+
+// Check if a match can go from one state to another (staying in the same state is always fine)
+func checkMatchTransition(from string, to string) error {
+	if !IsValidMatchState(to) {
+		return fmt.Errorf("%w: %q", ErrUnknownMatchState, to)
+	}
+	if from != to && !slices.Contains(matchTransitions[from], to) {
+		return fmt.Errorf("%w: can't go from %s to %s", ErrIllegalMatchTransition, from, to)
+	}
+	return nil
+}
+
 type Match struct {
 	Mutex      *sync.RWMutex
 	ID         int      // Unique id (by server)
@@ -21,6 +54,33 @@ type Match struct {
 	Game       string   // The gamemode the match is in
 	Players    []string // List of player ids in the match
 	TokenStore []string // List of tokens that can still be used
+
+	// Spectators have their own tokens and don't take any player slots
+	Spectators      []string
+	SpectatorTokens []string
+
+	// For telling the server when to start (see readiness.go)
+	MinPlayers      int           // The server is told the match is ready when this many players joined (0 to disable)
+	MaxPlayers      int           // No more players than this are put into the match (0 for as many as there are tokens)
+	ForceStartAfter time.Duration // The server is told to start this long after the match is ready (0 to disable)
+	ReadySince      time.Time     // Zero when the match doesn't have enough players yet
+	forceStartTimer *time.Timer
+
+	Reported     bool     // Whether the result has been reported already (see ratings.go)
+	Participants []string // Everyone that joined the match as a player (stays after they leave, so they can still be placed)
+
+	// Private matches are never picked for anyone, players need the join code (see private.go)
+	Private   bool
+	JoinCode  string
+	Password  string   // Empty when the code is enough
+	Allowlist []string // Only these accounts can join (everyone with the code when empty)
+}
+
+// Remember that a player joined the match (always lock the mutex before)
+func (m *Match) addParticipantNoMutex(account string) {
+	if !slices.Contains(m.Participants, account) {
+		m.Participants = append(m.Participants, account)
+	}
 }
 
 // Locks the mutex
@@ -32,41 +92,78 @@ func (m *Match) CanBeJoined() bool {
 }
 
 func (m *Match) canBeJoinedNoMutex() bool {
-	return m.State == MatchStateAccepting && len(m.TokenStore) > 0
+	return m.hasSlotsNoMutex(1)
+}
+
+// Check if a group of players could join the match
+func (m *Match) hasSlotsNoMutex(slots int) bool {
+	return m.State == MatchStateAccepting && len(m.TokenStore) >= slots && len(m.Players)+slots <= m.capacityNoMutex()
 }
 
 // Tries to add a player to the match (returns false if it didn't work)
 func (m *Match) AddPlayerIfPossible(id string) (string, bool) {
+	tokens, ok := m.AddPlayersIfPossible([]string{id})
+	if !ok {
+		return "", false
+	}
+	return tokens[0], true
+}
+
+// Tries to add a group of players to the match, either all of them get a token or none (tokens are in the same order as the ids)
+func (m *Match) AddPlayersIfPossible(ids []string) ([]string, bool) {
 	m.Mutex.Lock()
 	defer m.Mutex.Unlock()
 
-	if m.canBeJoinedNoMutex() {
-		m.Players = append(m.Players, id)
+	if len(ids) == 0 || !m.hasSlotsNoMutex(len(ids)) {
+		return nil, false
+	}
+	m.Players = append(m.Players, ids...)
 
-		// Remove the first token and remove it
-		token := m.TokenStore[0]
-		m.TokenStore = slices.Delete(m.TokenStore, 0, 1)
+	// Take the first tokens and remove them
+	tokens := slices.Clone(m.TokenStore[:len(ids)])
+	m.TokenStore = slices.Delete(m.TokenStore, 0, len(ids))
 
-		return token, true
-	}
-	return "", false
+	return tokens, true
 }
 
-// Remove all players in the match from the system
+// Remove all players and spectators in the match from the system
 func (m *Match) deleteAllPlayers() {
 	m.Mutex.RLock()
 	defer m.Mutex.RUnlock()
 
-	for _, player := range m.Players {
+	for _, player := range slices.Concat(m.Players, m.Spectators) {
 		go DeletePlayer(player, nil) // In a goroutine to make sure no mutex shit happens
 	}
 }
 
 type MatchRegistry struct {
-	Game             string
-	Mutex            *sync.RWMutex
-	currentlyFilling *Match
-	available        []*Match
+	Game         string
+	Mutex        *sync.RWMutex
+	available    []*Match      // All matches that haven't ended yet
+	accepting    []*Match      // Only the ones players can be sent to (in the same order as available)
+	selector     MatchSelector // How matches are chosen for players (see selectors.go)
+	selectorName string
+	rejoinWindow time.Duration // How long players can get back into their match after disconnecting (see rejoin.go)
+
+	// For players waiting for a slot (see queue.go)
+	queueMutex     *sync.Mutex
+	queue          []*QueueEntry // All entries still waiting (in the order they joined)
+	lastAssignment time.Time     // When the last player was assigned from the queue
+	assignInterval time.Duration // Average time between two assignments from the queue
+}
+
+func newMatchRegistry(game string) *MatchRegistry {
+	return &MatchRegistry{
+		Game:         game,
+		Mutex:        &sync.RWMutex{},
+		available:    []*Match{},
+		accepting:    []*Match{},
+		selector:     &FillFullestSelector{},
+		selectorName: SelectorFillFullest,
+		rejoinWindow: DefaultRejoinWindow,
+		queueMutex:   &sync.Mutex{},
+		queue:        []*QueueEntry{},
+	}
 }
 
 func (mr *MatchRegistry) GetMatch(id int) (*Match, bool) {
@@ -85,63 +182,152 @@ func (mr *MatchRegistry) GetMatch(id int) (*Match, bool) {
 // Add a match to the registry
 func (mr *MatchRegistry) AddMatch(match *Match) {
 	mr.Mutex.Lock()
-	defer mr.Mutex.Unlock()
+	mr.available = append(mr.available, match)
+	mr.Mutex.Unlock()
 
-	// Set currently filling when there isn't one
-	if mr.currentlyFilling == nil {
+	mr.reindex(match)
+}
 
-		// We use the write mutex to make sure the state isn't changed by something else, cause when it's changed it will be sorted too
-		match.Mutex.Lock()
-		if match.State == MatchStateAccepting {
-			mr.currentlyFilling = match
-		}
-		match.Mutex.Unlock()
+// Update the indexes of the registry right after the state of a match changed
+func (mr *MatchRegistry) reindex(match *Match) {
+	match.Mutex.RLock()
+	state, private := match.State, match.Private
+	match.Mutex.RUnlock()
+
+	if state == MatchStateEnd {
+		unregisterJoinCode(match)
 	}
 
-	// Add the match to the regular list
-	mr.available = append(mr.available, match)
-}
+	mr.Mutex.Lock()
+	defer mr.Mutex.Unlock()
 
-// nil if there isn't any match that is currently available
-func (mr *MatchRegistry) getAvailableMatch() *Match {
-	mr.Mutex.RLock()
-	if mr.currentlyFilling != nil && mr.currentlyFilling.CanBeJoined() {
-		return mr.currentlyFilling
+	if state == MatchStateEnd {
+		mr.available = slices.DeleteFunc(mr.available, func(m *Match) bool {
+			return m == match
+		})
+	}
+	if state != MatchStateAccepting || private {
+		mr.accepting = slices.DeleteFunc(mr.accepting, func(m *Match) bool {
+			return m == match
+		})
+		return
 	}
-	mr.Mutex.RUnlock()
 
-	// If there wasn't a currently filling match, we need to determine a new one
-	mr.setBestFillingMatch()
+	// Rebuilt from all matches to keep the order selectors rely on for ties
+	if !slices.Contains(mr.accepting, match) {
+		mr.accepting = slices.DeleteFunc(slices.Clone(mr.available), func(m *Match) bool {
+			return m != match && !slices.Contains(mr.accepting, m)
+		})
+	}
+}
 
+// Get the name of the selector used for choosing matches
+func (mr *MatchRegistry) Selector() string {
 	mr.Mutex.RLock()
 	defer mr.Mutex.RUnlock()
-	return mr.currentlyFilling
+	return mr.selectorName
+}
+
+// Change the selector used for choosing matches (nothing happens when it's already used, to keep its state)
+func (mr *MatchRegistry) setSelector(name string) bool {
+	mr.Mutex.Lock()
+	defer mr.Mutex.Unlock()
+	if mr.selectorName == name {
+		return true
+	}
+
+	selector, ok := NewMatchSelector(name)
+	if !ok {
+		return false
+	}
+	mr.selector = selector
+	mr.selectorName = name
+	return true
 }
 
-// Helper function for setting the best match to fill up next
-func (mr *MatchRegistry) setBestFillingMatch() {
+// nil if there isn't any match that currently has room for everyone in the request
+func (mr *MatchRegistry) getAvailableMatch(request SelectionRequest) *Match {
 
 	// Clean to make sure no shit happens
 	mr.cleanup()
 
-	mr.Mutex.Lock()
-	defer mr.Mutex.Unlock()
-	mr.currentlyFilling = nil
+	mr.Mutex.RLock()
+	selector := mr.selector
+	mr.Mutex.RUnlock()
+
+	// Players far away from the servers only get a match in case there isn't anything closer
+	candidates := filterByRegion(request, mr.getCandidates(len(request.Accounts)))
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	return selector.Select(request, candidates)
+}
 
-	currentSize := -1
-	for _, match := range mr.available {
-		if !match.CanBeJoined() {
+// Collect all matches that have enough slots for a group
+func (mr *MatchRegistry) getCandidates(slots int) []MatchCandidate {
+	mr.Mutex.RLock()
+	defer mr.Mutex.RUnlock()
+
+	candidates := []MatchCandidate{}
+	servers := map[int]MatchCandidate{} // Server id -> candidate with the server details filled in
+	ready := map[int]bool{}             // Server id -> whether players can be sent there
+	for _, match := range mr.accepting {
+		match.Mutex.RLock()
+		joinable := match.hasSlotsNoMutex(slots)
+		players := slices.Clone(match.Players)
+		match.Mutex.RUnlock()
+		if !joinable {
 			continue
 		}
 
-		// Set as currently filling if more players than current or nil
-		match.Mutex.RLock()
-		if mr.currentlyFilling == nil || currentSize < len(match.Players) {
-			mr.currentlyFilling = match
-			currentSize = len(match.Players)
+		// Servers that aren't ready (e.g. draining) keep their matches, but nobody new is sent there
+		if selectable, ok := ready[match.Server]; ok && !selectable {
+			continue
 		}
-		match.Mutex.RUnlock()
+		candidate, ok := servers[match.Server]
+		if !ok {
+			ready[match.Server] = isServerSelectable(match.Server)
+			if !ready[match.Server] {
+				continue
+			}
+
+			candidate = MatchCandidate{
+				Server:     match.Server,
+				ServerLoad: countServerPlayers(match.Server),
+			}
+			if server, ok := serverCache.Get(match.Server); ok {
+				server.Mutex.RLock()
+				candidate.Region, candidate.Tags = server.Region, server.Tags
+				server.Mutex.RUnlock()
+			}
+			servers[match.Server] = candidate
+		}
+
+		candidate.Match = match
+		candidate.Players = players
+		candidates = append(candidates, candidate)
 	}
+	return candidates
+}
+
+// Helper function for counting the players (and reservations) in all matches on a server
+func countServerPlayers(id int) int {
+	server, ok := serverCache.Get(id)
+	if !ok {
+		return 0
+	}
+
+	count := 0
+	server.Matches.Range(func(key, value any) bool {
+		match := value.(*Match)
+
+		match.Mutex.RLock()
+		defer match.Mutex.RUnlock()
+		count += len(match.Players)
+		return true
+	})
+	return count
 }
 
 // Helper function for cleaning up the match registry
@@ -149,14 +335,12 @@ func (mr *MatchRegistry) cleanup() {
 	mr.Mutex.Lock()
 	mr.available = slices.DeleteFunc(mr.available, func(m *Match) bool {
 		rem := mr.shouldBeRemoved(m)
-		if rem && mr.currentlyFilling == m {
-			mr.currentlyFilling = nil
-		}
 		if rem {
 			m.deleteAllPlayers()
 		}
 		return rem
 	})
+	mr.accepting = slices.DeleteFunc(mr.accepting, mr.shouldBeRemoved)
 	mr.Mutex.Unlock()
 }
 
@@ -1,7 +1,11 @@
 package service
 
 import (
+	"slices"
 	"sync"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/util"
 )
 
 // Game (string) -> *MatchRegistry
@@ -10,12 +14,31 @@ var gameCache = &sync.Map{}
 type MatchCreate struct {
 	ID   int    `json:"id"`   // Unique id (by server)
 	Game string `json:"game"` // The gamemode the match is in
+
+	Selector        string   `json:"selector,omitempty"` // How players are put into matches of the game (changes it for the whole game, see selectors.go)
+	SpectatorTokens []string `json:"-"`                  // Tokens for spectators (nobody can spectate when there are none)
+
+	// For getting told when to start the match (see readiness.go)
+	MinPlayers      int `json:"min_players,omitempty"`       // The server gets a match_ready event once this many players joined
+	MaxPlayers      int `json:"max_players,omitempty"`       // Defaults to the amount of tokens
+	ForceStartAfter int `json:"force_start_after,omitempty"` // Seconds after the match is ready until the server gets a match_force_start event
+
+	RejoinWindow int `json:"rejoin_window,omitempty"` // Seconds players of the game can rejoin after disconnecting (changes it for the whole game, negative to disable)
+
+	// Private matches are only joined using the code they get (see private.go)
+	Private   bool     `json:"private,omitempty"`
+	Password  string   `json:"password,omitempty"`
+	Allowlist []string `json:"allowlist,omitempty"`
 }
 
+// Length of the tokens created for matches advertised without any
+const MatchTokenLength = 32
+
 // Returns whether or not the match could be registered (state and stuff will be adjusted)
 func AddMatch(server int, data MatchCreate, tokens []string) bool {
 	info, ok := serverCache.Get(server)
-	if !ok {
+	if !ok || (data.Selector != "" && !IsValidMatchSelector(data.Selector)) || data.MinPlayers < 0 || data.MaxPlayers < 0 ||
+		(data.MaxPlayers > 0 && data.MinPlayers > data.MaxPlayers) {
 		return false
 	}
 
@@ -23,11 +46,23 @@ func AddMatch(server int, data MatchCreate, tokens []string) bool {
 	info.Mutex.RLock()
 	defer info.Mutex.RUnlock()
 
+	// Servers on their way out can't start anything new
+	if info.State == ServerStateStopping || info.State == ServerStateStopped {
+		return false
+	}
+
 	// Make sure the match doesn't already exist
 	if _, ok := info.Matches.Load(data.ID); ok {
 		return false
 	}
 
+	// Servers verifying join tickets don't need to send tokens, they are only used for confirming joins then
+	if len(tokens) == 0 {
+		for range data.MaxPlayers {
+			tokens = append(tokens, util.GenerateToken(MatchTokenLength))
+		}
+	}
+
 	// Initialize the match with the data from the request
 	match := &Match{
 		Mutex:      &sync.RWMutex{},
@@ -37,50 +72,94 @@ func AddMatch(server int, data MatchCreate, tokens []string) bool {
 		Players:    []string{},
 		TokenStore: tokens,
 		State:      MatchStateAvailable,
+
+		Spectators:      []string{},
+		SpectatorTokens: slices.Clone(data.SpectatorTokens),
+
+		MinPlayers:      data.MinPlayers,
+		MaxPlayers:      data.MaxPlayers,
+		ForceStartAfter: time.Duration(data.ForceStartAfter) * time.Second,
+
+		Private:   data.Private,
+		Password:  data.Password,
+		Allowlist: slices.Clone(data.Allowlist),
+	}
+	if match.Private {
+		match.JoinCode = registerJoinCode(match)
 	}
 
 	// Add to the game
 	info.Matches.Store(data.ID, match)
 	addMatchToGame(data.Game, match)
+	if data.Selector != "" {
+		getOrCreateRegistry(data.Game).setSelector(data.Selector)
+	}
+	if data.RejoinWindow != 0 {
+		getOrCreateRegistry(data.Game).setRejoinWindow(time.Duration(data.RejoinWindow) * time.Second)
+	}
 
 	return true
 }
 
 func addMatchToGame(game string, match *Match) {
-	obj, ok := gameCache.Load(game)
-	if !ok {
-		obj = &MatchRegistry{
-			Game:      game,
-			Mutex:     &sync.RWMutex{},
-			available: []*Match{},
-		}
-		gameCache.Store(game, obj)
+	getOrCreateRegistry(game).AddMatch(match)
+}
+
+// Get the match registry for a game (creates it in case it doesn't exist yet)
+func getOrCreateRegistry(game string) *MatchRegistry {
+	if obj, ok := gameCache.Load(game); ok {
+		return obj.(*MatchRegistry)
 	}
 
-	registry := obj.(*MatchRegistry)
-	registry.AddMatch(match)
+	obj, _ := gameCache.LoadOrStore(game, newMatchRegistry(game))
+	return obj.(*MatchRegistry)
 }
 
-// Returns false when it didn't work
+// Returns false when it didn't work (see TransitionMatch for why it wouldn't)
 func SetMatchState(server int, matchId int, state string) bool {
+	return TransitionMatch(server, matchId, state) == nil
+}
+
+// Move a match into another state (the error says why in case the match doesn't exist or can't go there)
+func TransitionMatch(server int, matchId int, state string) error {
 	match, ok := GetMatchFromServer(server, matchId)
 	if !ok {
-		return false
+		return ErrMatchNotFound
 	}
 
 	match.Mutex.Lock()
+	if err := checkMatchTransition(match.State, state); err != nil {
+		match.Mutex.Unlock()
+		return err
+	}
 	match.State = state
+	if state == MatchStateEnd {
+		match.stopForceStartNoMutex()
+	}
 	match.Mutex.Unlock()
 
-	// Delete the match when it ends
+	// Make sure the match leaves (or enters) the selection right away
+	mr, hasRegistry := GetMatchRegistry(match.Game)
+	if hasRegistry {
+		mr.reindex(match)
+	}
+
+	// Delete the match when it ends (and stop the server in case it was waiting for that)
 	if state == MatchStateEnd {
+		match.deleteAllPlayers()
 		server, ok := serverCache.Get(server)
 		if ok {
 			server.Matches.Delete(matchId)
+			stopServerIfDone(server)
 		}
-		return true
+		return nil
 	}
-	return true
+
+	// Give players waiting in the queue a chance to join
+	if state == MatchStateAccepting && hasRegistry {
+		mr.processQueue()
+	}
+	return nil
 }
 
 // Get the match registry for a game
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const MetricsNamespace = "hytale_matchmaking"
+
+// Registry containing all metrics exposed at /metrics
+var MetricsRegistry = prometheus.NewRegistry()
+
+var (
+	reservationsExpired = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "reservations_expired_total",
+		Help:      "Reservations that were freed because the player didn't join in time.",
+	})
+	serverEvictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "server_evictions_total",
+		Help:      "Servers that were removed because they stopped renewing.",
+	})
+	playerLeaves = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: MetricsNamespace,
+		Name:      "player_leaves_total",
+		Help:      "Players that were reported as gone by their server, by reason.",
+	}, []string{"reason"})
+	queueWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: MetricsNamespace,
+		Name:      "queue_wait_seconds",
+		Help:      "Time between joining the queue and getting a slot in a match.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300},
+	}, []string{"game"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: MetricsNamespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time it took to handle requests by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// Descriptions of the metrics that are calculated from the current state when scraped
+var (
+	serversDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(MetricsNamespace, "", "servers"),
+		"Servers that are currently registered.",
+		nil, nil,
+	)
+	tokensDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(MetricsNamespace, "", "tokens"),
+		"Tokens in the pool by whether they are used by a server.",
+		[]string{"state"}, nil,
+	)
+	matchesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(MetricsNamespace, "", "matches"),
+		"Matches on all servers by game and state.",
+		[]string{"game", "state"}, nil,
+	)
+	playersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(MetricsNamespace, "", "players"),
+		"Players by whether they are queued, have a reserved slot, joined their server or are spectating.",
+		[]string{"state"}, nil,
+	)
+	matchFillDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(MetricsNamespace, "", "match_fill_ratio"),
+		"How much of the capacity of matches is taken by players on average, by game (0 to 1).",
+		[]string{"game"}, nil,
+	)
+)
+
+func init() {
+	MetricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		reservationsExpired,
+		serverEvictions,
+		playerLeaves,
+		queueWait,
+		httpDuration,
+		stateCollector{},
+	)
+}
+
+// Handler serving all metrics in the Prometheus format
+func MetricsHandler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(MetricsRegistry, promhttp.HandlerOpts{}))
+}
+
+// Middleware for measuring how long requests take (by route, not path, to keep the amount of labels small)
+func MetricsMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			status = fiberErr.Code
+		}
+		httpDuration.WithLabelValues(c.Method(), c.Route().Path, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// Collects the gauges from the current state of the network on every scrape
+type stateCollector struct{}
+
+func (stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- serversDesc
+	ch <- tokensDesc
+	ch <- matchesDesc
+	ch <- playersDesc
+	ch <- matchFillDesc
+}
+
+func (stateCollector) Collect(ch chan<- prometheus.Metric) {
+	free, total := TokenPoolStats()
+	ch <- prometheus.MustNewConstMetric(tokensDesc, prometheus.GaugeValue, float64(free), "free")
+	ch <- prometheus.MustNewConstMetric(tokensDesc, prometheus.GaugeValue, float64(total-free), "used")
+
+	type matchKey struct {
+		game  string
+		state string
+	}
+	type fillSum struct {
+		total   float64
+		matches int
+	}
+	servers := 0
+	matches := map[matchKey]int{}
+	fills := map[string]*fillSum{} // Game -> fill of all running matches (per match labels would never stop growing)
+	reserved, confirmed, spectating := 0, 0, 0
+	rangeServers(func(id int, server *ServerInfo) bool {
+		servers++
+
+		server.Matches.Range(func(key, value any) bool {
+			match := value.(*Match)
+
+			match.Mutex.RLock()
+			defer match.Mutex.RUnlock()
+			matches[matchKey{game: match.Game, state: match.State}]++
+			if match.State != MatchStateEnd {
+				if fills[match.Game] == nil {
+					fills[match.Game] = &fillSum{}
+				}
+				fills[match.Game].total += match.fillNoMutex()
+				fills[match.Game].matches++
+			}
+			return true
+		})
+
+		server.Players.Range(func(key, value any) bool {
+			player := value.(*PlayerInfo)
+
+			player.Mutex.RLock()
+			defer player.Mutex.RUnlock()
+			if player.Spectator {
+				spectating++
+			} else if player.Confirmed {
+				confirmed++
+			} else {
+				reserved++
+			}
+			return true
+		})
+		return true
+	})
+
+	queued := 0
+	gameCache.Range(func(key, value any) bool {
+		mr := value.(*MatchRegistry)
+
+		mr.queueMutex.Lock()
+		defer mr.queueMutex.Unlock()
+		for _, entry := range mr.queue {
+			queued += len(entry.Accounts)
+		}
+		return true
+	})
+
+	ch <- prometheus.MustNewConstMetric(serversDesc, prometheus.GaugeValue, float64(servers))
+	for key, count := range matches {
+		ch <- prometheus.MustNewConstMetric(matchesDesc, prometheus.GaugeValue, float64(count), key.game, key.state)
+	}
+	for game, fill := range fills {
+		ch <- prometheus.MustNewConstMetric(matchFillDesc, prometheus.GaugeValue, fill.total/float64(fill.matches), game)
+	}
+	ch <- prometheus.MustNewConstMetric(playersDesc, prometheus.GaugeValue, float64(queued), "queued")
+	ch <- prometheus.MustNewConstMetric(playersDesc, prometheus.GaugeValue, float64(reserved), "reserved")
+	ch <- prometheus.MustNewConstMetric(playersDesc, prometheus.GaugeValue, float64(confirmed), "confirmed")
+	ch <- prometheus.MustNewConstMetric(playersDesc, prometheus.GaugeValue, float64(spectating), "spectating")
+}
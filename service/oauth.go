@@ -0,0 +1,166 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/util"
+)
+
+// Endpoints and client details for authenticating with Hytale
+const (
+	DeviceAuthURL  = "https://oauth.accounts.hytale.com/oauth2/device/auth"
+	TokenURL       = "https://oauth.accounts.hytale.com/oauth2/token"
+	ProfilesURL    = "https://account-data.hytale.com/my-account/get-profiles"
+	GameSessionURL = "https://sessions.hytale.com/game-session/new"
+	ClientID       = "hytale-server"
+	Scope          = "openid offline auth:server"
+)
+
+const (
+	TokenRefreshInterval = 30 * time.Second // How often the refresher checks for tokens that are about to expire
+	TokenRefreshMargin   = 5 * time.Minute  // Tokens are refreshed this long before they expire
+	DefaultTokenLifetime = time.Hour        // Used when the token endpoint doesn't tell us when a token expires
+	MaxTokenRefreshDelay = 30 * time.Minute // Longest a token waits before a failed refresh is tried again
+)
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+	Error        string `json:"error,omitempty"`
+}
+
+// Get the token endpoint (HYTALE_TOKEN_URL can be set to use a local stand-in instead)
+func GetTokenURL() string {
+	return hytaleEndpoint("HYTALE_TOKEN_URL", TokenURL)
+}
+
+// Helper function for getting an endpoint that can be replaced using the environment
+func hytaleEndpoint(env string, fallback string) string {
+	if value := os.Getenv(env); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Get when a token returned by the token endpoint will expire
+func (tr TokenResponse) Expiry() time.Time {
+	if tr.ExpiresIn <= 0 {
+		return time.Now().Add(DefaultTokenLifetime)
+	}
+	return time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+}
+
+// Start refreshing tokens and game sessions in the background before they expire
+func StartTokenRefresher() {
+	go func() {
+		for {
+			RefreshExpiringTokens()
+			RefreshExpiringSessions()
+			time.Sleep(TokenRefreshInterval)
+		}
+	}()
+}
+
+// Refresh all tokens that expire soon, used or not (returns how many were refreshed)
+func RefreshExpiringTokens() int {
+	expiring := []int{}
+	tokensMap.Range(func(key, value any) bool {
+		info := value.(*TokenInfo)
+		info.Mutex.Lock()
+		defer info.Mutex.Unlock()
+
+		if info.Token.RefreshToken != "" && time.Until(info.Token.ExpiresAt) < TokenRefreshMargin && time.Now().After(info.retryRefreshAt) {
+			expiring = append(expiring, info.Id)
+		}
+		return true
+	})
+
+	refreshed := 0
+	for _, id := range expiring {
+		err := RefreshToken(id)
+		backOffRefresh(id, err != nil)
+		if err != nil {
+			log.Println("Couldn't refresh token", id, ":", err)
+			continue
+		}
+		refreshed++
+	}
+	return refreshed
+}
+
+// Helper function for making tokens that keep failing to refresh wait longer every time (doubles up to MaxTokenRefreshDelay)
+func backOffRefresh(id int, failed bool) {
+	obj, ok := tokensMap.Load(id)
+	if !ok {
+		return
+	}
+	info := obj.(*TokenInfo)
+
+	info.Mutex.Lock()
+	defer info.Mutex.Unlock()
+	if !failed {
+		info.failedRefreshes = 0
+		info.retryRefreshAt = time.Time{}
+		return
+	}
+
+	info.failedRefreshes++
+	delay := TokenRefreshInterval << min(info.failedRefreshes, 10) // Capped so it can't overflow
+	info.retryRefreshAt = time.Now().Add(min(delay, MaxTokenRefreshDelay))
+}
+
+// Exchange the refresh token of a token for a new access token (the server using the token is told about the new one)
+func RefreshToken(id int) error {
+	obj, ok := tokensMap.Load(id)
+	if !ok {
+		return errors.New("token doesn't exist")
+	}
+	info := obj.(*TokenInfo)
+
+	info.Mutex.Lock()
+	refreshToken := info.Token.RefreshToken
+	info.Mutex.Unlock()
+
+	// OAuth endpoints require application/x-www-form-urlencoded
+	data := url.Values{}
+	data.Set("client_id", ClientID)
+	data.Set("grant_type", "refresh_token")
+	data.Set("refresh_token", refreshToken)
+
+	tokenResp, err := util.PostFormAllowErrors[TokenResponse](GetTokenURL(), data, util.Headers{})
+	if err != nil {
+		return err
+	}
+	if tokenResp.Error != "" {
+		return errors.New(tokenResp.Error)
+	}
+	if tokenResp.AccessToken == "" {
+		return errors.New("no access token returned")
+	}
+
+	info.Mutex.Lock()
+	info.Token.AccessToken = tokenResp.AccessToken
+	if tokenResp.RefreshToken != "" {
+		info.Token.RefreshToken = tokenResp.RefreshToken
+	}
+	info.Token.ExpiresAt = tokenResp.Expiry()
+	used := info.Used
+	info.Mutex.Unlock()
+
+	tokenCounterMutex.Lock()
+	saveToTokens()
+	tokenCounterMutex.Unlock()
+
+	// Tell the server using the token about the change
+	if used {
+		publishEvent(id, Event{Type: EventAccessTokenRotated})
+	}
+	return nil
+}
@@ -28,8 +28,9 @@ func init() {
 
 		OnEvict: func(item *ristretto.Item[CachedPlayer]) {
 
-			// Cleanup player
-			go DeletePlayer(item.Value.Id, nil)
+			// Cleanup player (the cached player has to be passed in since it's no longer in the cache, ristretto reuses the item)
+			cached := item.Value
+			cleanupGroup.Go(func() { expirePlayer(cached) })
 		},
 	})
 	if err != nil {
@@ -42,64 +43,178 @@ type PlayerInfo struct {
 	Account string
 	Server  int
 	Match   int
+	Party   *Party // Only set when the player was queued together with others
 
 	// For actual join behavior
 	Token     string
 	Confirmed bool
+	Spectator bool // Spectators use the spectator tokens of the match and don't take a player slot
+}
+
+// A group of players that got their slots in the same match together
+type Party struct {
+	Members []string
 }
 
 // Check if an account is in a match or the queue for one
 func IsOnServerOrWaiting(account string) bool {
+	if _, ok := queueCache.Get(account); ok {
+		return true
+	}
 	_, ok := PlayerCache.Get(account)
 	return ok
 }
 
 // nil if no match has available slots (returns the token and server id if success)
 func CreatePlayerIfPossible(game string, account string) (string, int, bool) {
-	mr, ok := GetMatchRegistry(game)
+	tokens, server, ok := CreatePartyIfPossible(game, []string{account})
 	if !ok {
 		return "", 0, false
 	}
+	return tokens[0], server, true
+}
 
-	// Find an available match (loops until there really isn't any slot available)
-	var playerToken string = ""
+// Reserve slots for all accounts in the same match, either everyone gets one or no-one (returns the tokens in the same order as the accounts and the server id)
+func CreatePartyIfPossible(game string, accounts []string) ([]string, int, bool) {
+	return createParty(SelectionRequest{
+		Game:     game,
+		Accounts: accounts,
+	})
+}
+
+// Helper function for reserving slots for accounts that might have been waiting already or prefer some region
+func createParty(request SelectionRequest) ([]string, int, bool) {
+	game, accounts := request.Game, request.Accounts
+	mr, ok := GetMatchRegistry(game)
+	if !ok || len(accounts) == 0 {
+		return nil, 0, false
+	}
+
+	// Make sure the same account can't get two slots
+	for _, account := range accounts {
+		if _, ok := PlayerCache.Get(account); ok {
+			return nil, 0, false
+		}
+	}
+
+	// Find an available match (loops until there really isn't any match with enough slots available)
+	var tokens []string
 	var match *Match
 	for {
-		match = mr.getAvailableMatch()
+		match = mr.getAvailableMatch(request)
 		if match == nil {
-			return "", 0, false
+			return nil, 0, false
 		}
 
-		if token, ok := match.AddPlayerIfPossible(account); ok {
-			playerToken = token
+		if reserved, ok := match.AddPlayersIfPossible(accounts); ok {
+			tokens = reserved
 			break
 		}
 	}
 
+	if !addReservations(match, accounts, tokens) {
+		return nil, 0, false
+	}
+	return tokens, match.Server, true
+}
+
+// Helper function for adding players that just got slots in a match (the tokens are in the same order as the accounts, the slots are given back when it didn't work)
+func addReservations(match *Match, accounts []string, tokens []string) bool {
+	var party *Party
+	if len(accounts) > 1 {
+		party = &Party{
+			Members: slices.Clone(accounts),
+		}
+	}
+
 	match.Mutex.RLock()
-	defer match.Mutex.RUnlock()
+	server, id := match.Server, match.ID
+	match.Mutex.RUnlock()
+
+	for i, account := range accounts {
+		player := &PlayerInfo{
+			Mutex:     &sync.RWMutex{},
+			Account:   account,
+			Server:    server,
+			Match:     id,
+			Party:     party,
+			Token:     tokens[i],
+			Confirmed: false,
+		}
+		if !addPlayer(server, account, player, true) {
+			releaseReservations(match, accounts[:i], accounts, tokens)
+			return false
+		}
 
-	player := &PlayerInfo{
-		Mutex:     &sync.RWMutex{},
-		Account:   account,
-		Server:    match.Server,
-		Match:     match.ID,
-		Token:     playerToken,
-		Confirmed: false,
+		publishEvent(server, Event{
+			Type: EventPlayerReserved,
+			Data: PlayerEvent{
+				Player: account,
+				Match:  id,
+				Token:  tokens[i],
+			},
+		})
 	}
-	if !addPlayer(match.Server, account, player, true) {
-		return "", 0, false
+	return true
+}
+
+// Helper function for giving back slots that couldn't be handed out (added are the accounts that already got a reservation)
+func releaseReservations(match *Match, added []string, accounts []string, tokens []string) {
+	if info, ok := serverCache.Get(match.Server); ok {
+		for _, account := range added {
+			info.Players.Delete(account)
+		}
 	}
-	return player.Token, player.Server, true
+	for _, account := range added {
+		PlayerCache.Del(account)
+	}
+	PlayerCache.Wait()
+
+	// The queue isn't processed here since this can happen while the queue is locked
+	match.Mutex.Lock()
+	defer match.Mutex.Unlock()
+	match.Players = slices.DeleteFunc(match.Players, func(p string) bool {
+		return slices.Contains(accounts, p)
+	})
+	match.TokenStore = append(match.TokenStore, tokens...)
+}
+
+// What the server needs to know about a player that joined
+type Confirmation struct {
+	Match     int
+	Spectator bool
 }
 
 // Make sure a player token is actually valid (returns true and matchId if the token has successfully been confirmed)
 func ConfirmPlayerToken(server int, account string, token string) (int, bool) {
+	confirmation, ok := ConfirmToken(server, account, token)
+	return confirmation.Match, ok
+}
+
+// Make sure the token of a player or spectator is actually valid (returns true if the token has successfully been confirmed)
+func ConfirmToken(server int, account string, token string) (Confirmation, bool) {
+	confirmation, match, ok := confirmToken(server, account, token)
+	if !ok {
+		return confirmation, false
+	}
+
+	forgetAssignment(account)
+	if !confirmation.Spectator {
+		match.Mutex.Lock()
+		match.addParticipantNoMutex(account)
+		match.Mutex.Unlock()
+		checkMatchReadiness(match)
+	}
+	return confirmation, true
+}
+
+// Helper function for confirming a token (also returns the match the player joined)
+func confirmToken(server int, account string, token string) (Confirmation, *Match, bool) {
 
 	// Make sure the player is actually valid
 	player, ok := getPlayer(account)
 	if !ok || player.Confirmed || player.Token != token {
-		return 0, false
+		return Confirmation{}, nil, false
 	}
 
 	player.Mutex.RLock()
@@ -108,16 +223,20 @@ func ConfirmPlayerToken(server int, account string, token string) (int, bool) {
 	match, ok := GetMatchFromServer(server, player.Match)
 	if !ok {
 		player.Mutex.RUnlock()
-		return 0, false
+		return Confirmation{}, nil, false
 	}
 
 	match.Mutex.RLock()
 	defer match.Mutex.RUnlock()
 
 	// Make sure the player has actually been accepted for the match
-	if !slices.Contains(match.Players, account) {
+	accepted := match.Players
+	if player.Spectator {
+		accepted = match.Spectators
+	}
+	if !slices.Contains(accepted, account) {
 		player.Mutex.RUnlock()
-		return 0, false
+		return Confirmation{}, nil, false
 	}
 
 	player.Mutex.RUnlock()
@@ -126,7 +245,10 @@ func ConfirmPlayerToken(server int, account string, token string) (int, bool) {
 
 	player.Confirmed = true
 	addPlayer(server, account, player, false) // Add to make sure they don't get removed by the timeout anymore
-	return player.Match, true
+	return Confirmation{
+		Match:     player.Match,
+		Spectator: player.Spectator,
+	}, match, true
 }
 
 // Helper function for adding a player to the cache
@@ -137,6 +259,7 @@ func addPlayer(server int, account string, player *PlayerInfo, timeout bool) boo
 	}
 
 	info.Players.Store(account, player)
+	rejoinCache.Del(account) // Players that got a slot somewhere else can't go back anymore
 	if timeout {
 		PlayerCache.SetWithTTL(account, CachedPlayer{
 			Id:     account,
@@ -176,8 +299,42 @@ func getPlayerFromCached(player CachedPlayer) (*PlayerInfo, bool) {
 	return pObj.(*PlayerInfo), true
 }
 
+// Helper function for cleaning up a player after they have been evicted from the cache
+func expirePlayer(cached CachedPlayer) {
+
+	// Don't touch the account in case it has been added again since
+	if _, ok := PlayerCache.Get(cached.Id); ok {
+		return
+	}
+
+	if info, ok := getPlayerFromCached(cached); ok {
+		info.Mutex.RLock()
+		confirmed, match := info.Confirmed, info.Match
+		info.Mutex.RUnlock()
+
+		// Let the server know the slot is free again
+		if !confirmed {
+			reservationsExpired.Inc()
+			publishEvent(cached.Server, Event{
+				Type: EventReservationExpired,
+				Data: PlayerEvent{
+					Player: cached.Id,
+					Match:  match,
+				},
+			})
+		}
+	}
+
+	DeletePlayer(cached.Id, &cached)
+}
+
 // Helper function for deleting a player from everywhere they leave a trace (set the player info if deleted straight from the cache)
 func DeletePlayer(account string, cached *CachedPlayer) {
+	deletePlayer(account, cached, false)
+}
+
+// Delete a player, retired tokens aren't given back to the match (e.g. because it's already running and the slot shouldn't be filled again)
+func deletePlayer(account string, cached *CachedPlayer, retire bool) {
 	if cached == nil {
 		var ok bool
 		obj, ok := PlayerCache.Get(account)
@@ -186,6 +343,7 @@ func DeletePlayer(account string, cached *CachedPlayer) {
 		}
 		cached = &obj
 	}
+	forgetAssignment(account)
 
 	info, ok := getPlayerFromCached(*cached)
 	if !ok {
@@ -193,29 +351,79 @@ func DeletePlayer(account string, cached *CachedPlayer) {
 	}
 
 	info.Mutex.RLock()
-	defer info.Mutex.RUnlock()
+	server, matchId, token := info.Server, info.Match, info.Token
+	party, confirmed, spectator := info.Party, info.Confirmed, info.Spectator
+	info.Mutex.RUnlock()
 
 	// Delete the player from the server
-	srv, ok := serverCache.Get(info.Server)
+	var freed *Match
+	srv, ok := serverCache.Get(server)
 	if ok {
 		srv.Players.Delete(account)
 
 		// Delete the player from the match they were in
-		m, ok := GetMatchFromServer(info.Server, info.Match)
+		m, ok := GetMatchFromServer(server, matchId)
 		if ok {
 			m.Mutex.Lock()
-			defer m.Mutex.Unlock()
-
-			// Remove the actual account from the match's player list
-			m.Players = slices.DeleteFunc(m.Players, func(p string) bool {
-				return p == account
-			})
-
-			// Make their token available again
-			m.TokenStore = append(m.TokenStore, info.Token)
+			if spectator {
+
+				// Spectators only give back their spectator token
+				m.Spectators = slices.DeleteFunc(m.Spectators, func(p string) bool {
+					return p == account
+				})
+				m.SpectatorTokens = append(m.SpectatorTokens, token)
+			} else {
+
+				// Remove the actual account from the match's player list
+				m.Players = slices.DeleteFunc(m.Players, func(p string) bool {
+					return p == account
+				})
+
+				// Make their token available again
+				if !retire && token != "" {
+					m.TokenStore = append(m.TokenStore, token)
+				}
+				freed = m
+			}
+			m.Mutex.Unlock()
 		}
 	}
 
 	PlayerCache.Del(account)
 	PlayerCache.Wait()
+
+	// When someone in a party didn't make it, the reservations of the others that didn't join yet are rolled back too
+	if party != nil && !confirmed {
+		releaseUnconfirmedMembers(party, account)
+	}
+
+	// Give the freed slot to the next player waiting for the game
+	if freed != nil {
+		checkMatchReadiness(freed)
+		if mr, ok := GetMatchRegistry(freed.Game); ok {
+			mr.processQueue()
+		}
+	}
+}
+
+// Helper function for deleting all members of a party that haven't confirmed their token yet
+func releaseUnconfirmedMembers(party *Party, except string) {
+	for _, member := range party.Members {
+		if member == except {
+			continue
+		}
+
+		player, ok := getPlayer(member)
+		if !ok {
+			continue
+		}
+
+		player.Mutex.RLock()
+		release := player.Party == party && !player.Confirmed
+		player.Mutex.RUnlock()
+
+		if release {
+			DeletePlayer(member, nil)
+		}
+	}
 }
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/Liphium/hytale-matchmaking/util"
+)
+
+// Length of the codes players use to join private matches
+const JoinCodeLength = 8
+
+var (
+	ErrUnknownJoinCode = errors.New("unknown join code")
+	ErrWrongPassword   = errors.New("wrong password")
+	ErrNotAllowed      = errors.New("account isn't on the allowlist")
+	ErrAlreadyPlaying  = errors.New("account already has a slot or is queued")
+	ErrMatchFull       = errors.New("match can't be joined right now")
+)
+
+// Join code -> *Match (only private matches that haven't ended yet)
+var joinCodes = &sync.Map{}
+
+// Give a private match a code nobody else is using (also registers it)
+func registerJoinCode(match *Match) string {
+	for {
+		code := util.GenerateToken(JoinCodeLength)
+		if _, loaded := joinCodes.LoadOrStore(code, match); !loaded {
+			return code
+		}
+	}
+}
+
+// Remove the code of a private match so nobody can use it anymore
+func unregisterJoinCode(match *Match) {
+	match.Mutex.RLock()
+	code := match.JoinCode
+	match.Mutex.RUnlock()
+	if code != "" {
+		joinCodes.CompareAndDelete(code, match)
+	}
+}
+
+// Get the code of a private match (false when the match doesn't exist or isn't private)
+func GetJoinCode(server int, matchId int) (string, bool) {
+	match, ok := GetMatchFromServer(server, matchId)
+	if !ok {
+		return "", false
+	}
+
+	match.Mutex.RLock()
+	defer match.Mutex.RUnlock()
+	return match.JoinCode, match.Private
+}
+
+// Reserve a slot in the private match behind a code (the error says why it didn't work)
+func QueueByCode(code string, account string, password string) (QueueStatus, error) {
+	obj, ok := joinCodes.Load(code)
+	if !ok {
+		return QueueStatus{}, ErrUnknownJoinCode
+	}
+	match := obj.(*Match)
+
+	match.Mutex.RLock()
+	wrongPassword := match.Password != "" && !equalSecrets(password, match.Password)
+	allowed := len(match.Allowlist) == 0 || slices.Contains(match.Allowlist, account)
+	game := match.Game
+	match.Mutex.RUnlock()
+	if wrongPassword {
+		return QueueStatus{}, ErrWrongPassword
+	}
+	if !allowed {
+		return QueueStatus{}, ErrNotAllowed
+	}
+
+	// Checked with the queue locked, otherwise two requests for the same player could both get a slot
+	mr := getOrCreateRegistry(game)
+	mr.queueMutex.Lock()
+	defer mr.queueMutex.Unlock()
+
+	if IsOnServerOrWaiting(account) {
+		return QueueStatus{}, ErrAlreadyPlaying
+	}
+	tokens, ok := match.AddPlayersIfPossible([]string{account})
+	if !ok {
+		return QueueStatus{}, ErrMatchFull
+	}
+	if !addReservations(match, []string{account}, tokens) {
+		return QueueStatus{}, ErrMatchFull
+	}
+
+	region, _ := GetServerRegion(match.Server)
+	return QueueStatus{
+		Assigned: true,
+		Server:   match.Server,
+		Region:   region,
+		Tokens:   map[string]string{account: tokens[0]},
+	}, nil
+}
@@ -0,0 +1,345 @@
+package service
+
+import (
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/ristretto/v2"
+)
+
+const QueueEntryTTL = 5 * time.Minute
+const QueueProcessInterval = time.Second // How often queues are checked again (for selectors that depend on how long players waited)
+
+type QueueEntry struct {
+	Mutex    *sync.RWMutex
+	Accounts []string // All accounts queued together (only one for solo players)
+	Game     string
+	Regions  []string // Where the players would like to play (closest first)
+	Joined   time.Time
+
+	// Set once slots in a match have been reserved for everyone
+	Assigned bool
+	Tokens   []string // In the same order as the accounts
+	Server   int
+}
+
+type QueueStatus struct {
+	Assigned      bool
+	Server        int
+	Region        string            // Region of the server (only set when assigned)
+	Tokens        map[string]string // Account -> token (only set when assigned)
+	Position      int               // Position in the queue (starting at 1, 0 when assigned)
+	EstimatedWait time.Duration     // 0 when there isn't enough data for an estimate yet
+	Rejoin        bool              // Whether the player got back into the match they were in before
+}
+
+// Account -> *QueueEntry (for all players waiting in a queue or that have recently been assigned from one)
+var queueCache *ristretto.Cache[string, *QueueEntry]
+
+func init() {
+	var err error
+	queueCache, err = ristretto.NewCache(&ristretto.Config[string, *QueueEntry]{
+		MaxCost:     10_000,      // Maximum 10.000 stored items
+		NumCounters: 10_000 * 10, // 10x what we want to store
+		BufferItems: 64,          // Read description of field
+
+		OnEvict: func(item *ristretto.Item[*QueueEntry]) {
+
+			// Remove the entry from the queue it's waiting in (there's no value when it was deleted already)
+			entry := item.Value
+			if entry == nil {
+				return
+			}
+			cleanupGroup.Go(func() { removeFromQueue(entry) })
+		},
+	})
+	if err != nil {
+		log.Fatalln("couldn't create cache:", err)
+	}
+}
+
+// Put a player into the queue of a game (false when the player already has a slot or is waiting for another game)
+func QueuePlayer(game string, account string) (QueueStatus, bool) {
+	return QueueParty(game, []string{account})
+}
+
+// Put a group of players into the queue of a game, they will all get slots in the same match (false when one of them already has a slot or is waiting somewhere else)
+func QueueParty(game string, accounts []string) (QueueStatus, bool) {
+	return QueuePartyWithPreference(game, accounts, RegionPreference{})
+}
+
+// Same as QueueParty, but matches on servers close to the players are preferred
+func QueuePartyWithPreference(game string, accounts []string, preference RegionPreference) (QueueStatus, bool) {
+	if len(accounts) == 0 || len(slices.Compact(slices.Sorted(slices.Values(accounts)))) != len(accounts) {
+		return QueueStatus{}, false
+	}
+
+	mr := getOrCreateRegistry(game)
+	entry := &QueueEntry{
+		Mutex:    &sync.RWMutex{},
+		Accounts: slices.Clone(accounts),
+		Game:     game,
+		Regions:  preference.Regions(),
+		Joined:   time.Now(),
+	}
+
+	// Everything is checked with the queue locked, otherwise two requests for the same players could both get in
+	mr.queueMutex.Lock()
+	defer mr.queueMutex.Unlock()
+
+	// Return the current position in case the same group is already waiting
+	if waiting, ok := queueCache.Get(accounts[0]); ok {
+		waiting.Mutex.RLock()
+		assigned := waiting.Assigned
+		waiting.Mutex.RUnlock()
+		if waiting.Game != game || !slices.Equal(waiting.Accounts, accounts) || assigned {
+			return QueueStatus{}, false
+		}
+		return mr.statusNoMutex(waiting), true
+	}
+
+	for _, account := range accounts {
+		if IsOnServerOrWaiting(account) {
+			return QueueStatus{}, false
+		}
+	}
+
+	// Players that disconnected get back into their match instead of a new one
+	if len(accounts) == 1 {
+		if status, ok := rejoinMatch(game, accounts[0]); ok {
+			return status, true
+		}
+	}
+
+	// Players can only skip the queue when no-one else is waiting
+	if len(mr.queue) == 0 {
+		if tokens, server, ok := createParty(entry.request()); ok {
+			entry.assign(tokens, server)
+			return entry.status(), true
+		}
+	}
+
+	mr.queue = append(mr.queue, entry)
+	for _, account := range accounts {
+		queueCache.SetWithTTL(account, entry, 1, QueueEntryTTL)
+	}
+	queueCache.Wait()
+
+	return mr.statusNoMutex(entry), true
+}
+
+// Get the status of a player in the queue (false if the player isn't queued)
+func GetQueueStatus(account string) (QueueStatus, bool) {
+	entry, ok := queueCache.Get(account)
+	if !ok {
+		return QueueStatus{}, false
+	}
+
+	entry.Mutex.RLock()
+	assigned := entry.Assigned
+	entry.Mutex.RUnlock()
+
+	if assigned {
+		return entry.status(), true
+	}
+
+	mr, ok := GetMatchRegistry(entry.Game)
+	if !ok {
+		return QueueStatus{}, false
+	}
+
+	mr.queueMutex.Lock()
+	defer mr.queueMutex.Unlock()
+	return mr.statusNoMutex(entry), true
+}
+
+// Remove a player (and everyone queued with them) from the queue, reservations are released for everyone that didn't join yet
+func CancelQueue(account string) bool {
+	entry, ok := queueCache.Get(account)
+	if !ok {
+		return false
+	}
+	for _, account := range entry.Accounts {
+		queueCache.Del(account)
+	}
+	queueCache.Wait()
+
+	entry.Mutex.RLock()
+	assigned := entry.Assigned
+	entry.Mutex.RUnlock()
+
+	if !assigned {
+		removeFromQueue(entry)
+		return true
+	}
+
+	// Only release the slots of players that didn't join the server yet
+	for _, account := range entry.Accounts {
+		player, ok := getPlayer(account)
+		if !ok {
+			continue
+		}
+
+		player.Mutex.RLock()
+		confirmed := player.Confirmed
+		player.Mutex.RUnlock()
+
+		if !confirmed {
+			DeletePlayer(account, nil)
+		}
+	}
+	return true
+}
+
+// Start checking all queues in the background (selectors might accept players after they waited for a while)
+func StartQueueProcessor() {
+	go func() {
+		for {
+			time.Sleep(QueueProcessInterval)
+			gameCache.Range(func(key, value any) bool {
+				value.(*MatchRegistry).processQueue()
+				return true
+			})
+		}
+	}()
+}
+
+// Assign as many waiting players as possible to matches (in the order they joined the queue)
+func (mr *MatchRegistry) processQueue() {
+	mr.queueMutex.Lock()
+	defer mr.queueMutex.Unlock()
+
+	for i := 0; i < len(mr.queue); {
+		entry := mr.queue[i]
+
+		// Drop entries where someone found their way onto a server some other way
+		if slices.ContainsFunc(entry.Accounts, func(account string) bool {
+			_, ok := PlayerCache.Get(account)
+			return ok
+		}) {
+			mr.queue = slices.Delete(mr.queue, i, i+1)
+			for _, account := range entry.Accounts {
+				queueCache.Del(account)
+			}
+			continue
+		}
+
+		tokens, server, ok := createParty(entry.request())
+		if !ok {
+
+			// When there isn't a single free slot there is no reason to look further (selectors might still accept someone else)
+			if len(entry.Accounts) == 1 && len(mr.getCandidates(1)) == 0 {
+				break
+			}
+
+			// Parties keep their position, but players behind them can still fill up smaller gaps
+			i++
+			continue
+		}
+
+		mr.queue = slices.Delete(mr.queue, i, i+1)
+		mr.trackAssignment()
+		entry.assign(tokens, server)
+	}
+}
+
+// Update the average time between two assignments (always lock the queue mutex before)
+func (mr *MatchRegistry) trackAssignment() {
+	now := time.Now()
+	if !mr.lastAssignment.IsZero() {
+		interval := now.Sub(mr.lastAssignment)
+		if mr.assignInterval == 0 {
+			mr.assignInterval = interval
+		} else {
+			mr.assignInterval = (mr.assignInterval*3 + interval) / 4
+		}
+	}
+	mr.lastAssignment = now
+}
+
+// Get the status of an entry still waiting in the queue (always lock the queue mutex before)
+func (mr *MatchRegistry) statusNoMutex(entry *QueueEntry) QueueStatus {
+	position := slices.Index(mr.queue, entry) + 1
+	return QueueStatus{
+		Position:      position,
+		EstimatedWait: mr.assignInterval * time.Duration(position),
+	}
+}
+
+// Helper function for marking an entry as assigned (the entry is kept until the reservations time out)
+func (e *QueueEntry) assign(tokens []string, server int) {
+	e.Mutex.Lock()
+	e.Assigned = true
+	e.Tokens = tokens
+	e.Server = server
+	e.Mutex.Unlock()
+
+	queueWait.WithLabelValues(e.Game).Observe(time.Since(e.Joined).Seconds())
+
+	for _, account := range e.Accounts {
+		queueCache.SetWithTTL(account, e, 1, PlayerTokenTimeout)
+	}
+	queueCache.Wait()
+}
+
+// Forget the assignment of a player once it was confirmed or given up (so they can queue again right away)
+func forgetAssignment(account string) {
+	entry, ok := queueCache.Get(account)
+	if !ok {
+		return
+	}
+
+	entry.Mutex.RLock()
+	assigned := entry.Assigned
+	entry.Mutex.RUnlock()
+	if assigned {
+		queueCache.Del(account)
+		queueCache.Wait()
+	}
+}
+
+// Helper function for turning an entry into a request for the match selector
+func (e *QueueEntry) request() SelectionRequest {
+	return SelectionRequest{
+		Game:     e.Game,
+		Accounts: e.Accounts,
+		Regions:  e.Regions,
+		Waited:   time.Since(e.Joined),
+	}
+}
+
+func (e *QueueEntry) status() QueueStatus {
+	e.Mutex.RLock()
+	defer e.Mutex.RUnlock()
+
+	tokens := map[string]string{}
+	for i, account := range e.Accounts {
+		tokens[account] = e.Tokens[i]
+	}
+
+	status := QueueStatus{
+		Assigned: e.Assigned,
+		Server:   e.Server,
+		Tokens:   tokens,
+	}
+	if e.Assigned {
+		status.Region, _ = GetServerRegion(e.Server)
+	}
+	return status
+}
+
+// Helper function for removing an entry from the queue of its game
+func removeFromQueue(entry *QueueEntry) {
+	mr, ok := GetMatchRegistry(entry.Game)
+	if !ok {
+		return
+	}
+
+	mr.queueMutex.Lock()
+	defer mr.queueMutex.Unlock()
+	mr.queue = slices.DeleteFunc(mr.queue, func(e *QueueEntry) bool {
+		return e == entry
+	})
+}
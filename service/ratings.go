@@ -0,0 +1,170 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"os"
+	"path"
+	"slices"
+	"sync"
+	"time"
+)
+
+const RatingsFileName = "ratings.json"
+
+const (
+	DefaultRating      = 1000.0 // Rating of players that haven't played a game yet
+	RatingK            = 32.0   // How much a single match can change a rating (split across all opponents)
+	SkillWindow        = 100.0  // How far the average rating of a match can be away from a player's rating at first
+	SkillWindowGrowth  = 10.0   // How much the window grows for every second a player has been waiting
+	MaximumSkillWindow = 1000.0 // The window doesn't grow further than this
+)
+
+// Game -> account -> rating
+var ratings = map[string]map[string]float64{}
+var ratingsMutex = &sync.RWMutex{}
+
+// Load all ratings from the ratings file (nothing happens in case it doesn't exist yet)
+func LoadRatings() {
+	content, err := os.ReadFile(path.Join(os.Getenv("TOKEN_FILE_LOCATION"), RatingsFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		log.Fatalln("Couldn't read ratings file:", err)
+	}
+
+	ratingsMutex.Lock()
+	defer ratingsMutex.Unlock()
+	if err := json.Unmarshal(content, &ratings); err != nil {
+		log.Fatalln("Couldn't parse ratings file:", err)
+	}
+}
+
+// Get the rating of a player in a game
+func GetRating(game string, account string) float64 {
+	ratingsMutex.RLock()
+	defer ratingsMutex.RUnlock()
+	return getRatingNoMutex(game, account)
+}
+
+// Always lock the ratings mutex before
+func getRatingNoMutex(game string, account string) float64 {
+	if rating, ok := ratings[game][account]; ok {
+		return rating
+	}
+	return DefaultRating
+}
+
+// Helper function for getting the average rating of a group of players (ok is false when there are no players)
+func averageRating(game string, accounts []string) (float64, bool) {
+	if len(accounts) == 0 {
+		return 0, false
+	}
+
+	ratingsMutex.RLock()
+	defer ratingsMutex.RUnlock()
+
+	sum := 0.0
+	for _, account := range accounts {
+		sum += getRatingNoMutex(game, account)
+	}
+	return sum / float64(len(accounts)), true
+}
+
+// Update the ratings of everyone in a match (placements start with the winners, players in the same placement tied, returns the new ratings)
+// Everyone placed has to have joined the match as a player (only once, leaving doesn't matter) and each match can only be reported once.
+func ReportMatchResult(server int, matchId int, placements [][]string) (map[string]float64, bool) {
+	match, ok := GetMatchFromServer(server, matchId)
+	if !ok || len(placements) < 2 {
+		return nil, false
+	}
+
+	// Make sure nobody was made up or placed twice
+	placed := map[string]bool{}
+	match.Mutex.Lock()
+	for _, placement := range placements {
+		for _, account := range placement {
+			if placed[account] || !slices.Contains(match.Participants, account) {
+				match.Mutex.Unlock()
+				return nil, false
+			}
+			placed[account] = true
+		}
+	}
+	if match.Reported || len(placed) < 2 {
+		match.Mutex.Unlock()
+		return nil, false
+	}
+	match.Reported = true
+	game := match.Game
+	match.Mutex.Unlock()
+
+	ratingsMutex.Lock()
+	defer ratingsMutex.Unlock()
+
+	// Everyone plays a game of Elo against everyone in a different placement
+	k := RatingK / float64(len(placed)-1)
+
+	changes := map[string]float64{}
+	for i, placement := range placements {
+		for _, account := range placement {
+			rating := getRatingNoMutex(game, account)
+
+			for j, opponents := range placements {
+				for _, opponent := range opponents {
+					if opponent == account {
+						continue
+					}
+
+					score := 0.5
+					if i < j {
+						score = 1
+					} else if i > j {
+						score = 0
+					}
+					expected := 1 / (1 + math.Pow(10, (getRatingNoMutex(game, opponent)-rating)/400))
+					changes[account] += k * (score - expected)
+				}
+			}
+		}
+	}
+
+	if ratings[game] == nil {
+		ratings[game] = map[string]float64{}
+	}
+	updated := map[string]float64{}
+	for account, change := range changes {
+		ratings[game][account] = getRatingNoMutex(game, account) + change
+		updated[account] = ratings[game][account]
+	}
+
+	saveRatings()
+	return updated, true
+}
+
+// Get how far the average rating of a match can be away from a player's rating after waiting for some time
+func skillWindow(waited time.Duration) float64 {
+	return min(SkillWindow+SkillWindowGrowth*waited.Seconds(), MaximumSkillWindow)
+}
+
+// Always lock the ratings mutex before
+func saveRatings() {
+	bytes, err := json.Marshal(ratings)
+	if err != nil {
+		log.Println("Couldn't write ratings file (marshal):", err)
+		return
+	}
+	if err := os.WriteFile(path.Join(os.Getenv("TOKEN_FILE_LOCATION"), RatingsFileName), bytes, 0644); err != nil {
+		log.Println("Couldn't write ratings file:", err)
+	}
+}
+
+// Helper function for forgetting all ratings (for tests)
+func clearRatings() {
+	ratingsMutex.Lock()
+	defer ratingsMutex.Unlock()
+	ratings = map[string]map[string]float64{}
+}
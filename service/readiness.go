@@ -0,0 +1,156 @@
+package service
+
+import (
+	"time"
+)
+
+// Everything about whether a match has enough players to start
+type MatchReadiness struct {
+	Players    int // Players in the match (including the ones that didn't join yet)
+	Confirmed  int // Players that actually joined
+	MinPlayers int
+	MaxPlayers int
+	Fill       float64 // Share of the capacity that's taken (0 to 1)
+
+	Ready        bool
+	ReadySince   time.Time // When the match reached the minimum players
+	ForceStartAt time.Time // Zero when the match won't be force started
+}
+
+// Get how many players fit into the match (always lock the mutex before)
+func (m *Match) capacityNoMutex() int {
+	if m.MaxPlayers > 0 {
+		return m.MaxPlayers
+	}
+	return len(m.Players) + len(m.TokenStore)
+}
+
+// Get how much of the match is filled up (always lock the mutex before)
+func (m *Match) fillNoMutex() float64 {
+	capacity := m.capacityNoMutex()
+	if capacity == 0 {
+		return 1
+	}
+	return float64(len(m.Players)) / float64(capacity)
+}
+
+// Get the readiness of a match
+func GetMatchReadiness(server int, matchId int) (MatchReadiness, bool) {
+	match, ok := GetMatchFromServer(server, matchId)
+	if !ok {
+		return MatchReadiness{}, false
+	}
+	confirmed := countConfirmedPlayers(match)
+
+	match.Mutex.RLock()
+	defer match.Mutex.RUnlock()
+
+	readiness := MatchReadiness{
+		Players:    len(match.Players),
+		Confirmed:  confirmed,
+		MinPlayers: match.MinPlayers,
+		MaxPlayers: match.capacityNoMutex(),
+		Fill:       match.fillNoMutex(),
+		Ready:      !match.ReadySince.IsZero(),
+		ReadySince: match.ReadySince,
+	}
+	if readiness.Ready && match.ForceStartAfter > 0 {
+		readiness.ForceStartAt = match.ReadySince.Add(match.ForceStartAfter)
+	}
+	return readiness, true
+}
+
+// Tell the server when a match reached (or dropped below) its minimum players
+func checkMatchReadiness(match *Match) {
+	confirmed := countConfirmedPlayers(match)
+
+	match.Mutex.Lock()
+	defer match.Mutex.Unlock()
+	if match.MinPlayers <= 0 || match.State == MatchStateEnd {
+		return
+	}
+
+	ready := confirmed >= match.MinPlayers
+	switch {
+	case ready && match.ReadySince.IsZero():
+		match.ReadySince = time.Now()
+		publishEvent(match.Server, Event{
+			Type: EventMatchReady,
+			Data: MatchEvent{
+				Match: match.ID,
+			},
+		})
+
+		// Tell the server to start anyway after some time (in case it's waiting for more players)
+		if match.ForceStartAfter > 0 {
+			readySince := match.ReadySince
+			match.forceStartTimer = time.AfterFunc(match.ForceStartAfter, func() {
+				forceStartMatch(match, readySince)
+			})
+		}
+
+	case !ready && !match.ReadySince.IsZero():
+		match.ReadySince = time.Time{}
+		match.stopForceStartNoMutex()
+		publishEvent(match.Server, Event{
+			Type: EventMatchReadyCancelled,
+			Data: MatchEvent{
+				Match: match.ID,
+			},
+		})
+	}
+}
+
+// Stop the timer for force starting the match (always lock the mutex before)
+func (m *Match) stopForceStartNoMutex() {
+	if m.forceStartTimer != nil {
+		m.forceStartTimer.Stop()
+		m.forceStartTimer = nil
+	}
+}
+
+// Helper function for telling the server to start a match in case it's still ready since the same time
+func forceStartMatch(match *Match, readySince time.Time) {
+	match.Mutex.Lock()
+	defer match.Mutex.Unlock()
+	if match.State == MatchStateEnd || !match.ReadySince.Equal(readySince) {
+		return
+	}
+	match.forceStartTimer = nil
+
+	publishEvent(match.Server, Event{
+		Type: EventMatchForceStart,
+		Data: MatchEvent{
+			Match: match.ID,
+		},
+	})
+}
+
+// Helper function for counting the players in a match that actually joined
+func countConfirmedPlayers(match *Match) int {
+	match.Mutex.RLock()
+	players := append([]string{}, match.Players...)
+	server := match.Server
+	match.Mutex.RUnlock()
+
+	info, ok := serverCache.Get(server)
+	if !ok {
+		return 0
+	}
+
+	confirmed := 0
+	for _, account := range players {
+		obj, ok := info.Players.Load(account)
+		if !ok {
+			continue
+		}
+		player := obj.(*PlayerInfo)
+
+		player.Mutex.RLock()
+		if player.Confirmed {
+			confirmed++
+		}
+		player.Mutex.RUnlock()
+	}
+	return confirmed
+}
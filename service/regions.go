@@ -0,0 +1,87 @@
+package service
+
+import (
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// How long players only get matches in their closest region before other regions are considered too
+const DefaultRegionFallbackWait = 30 * time.Second
+
+var regionFallbackWait atomic.Int64
+
+func init() {
+	regionFallbackWait.Store(int64(DefaultRegionFallbackWait))
+}
+
+// Where a player would like to play (the region is preferred over the pings)
+type RegionPreference struct {
+	Region string         `json:"region,omitempty"`
+	Pings  map[string]int `json:"pings,omitempty"` // Region -> measured ping in milliseconds
+}
+
+// Load the region settings from the environment
+func SetupRegions() {
+	if value := os.Getenv("REGION_FALLBACK_WAIT"); value != "" {
+		wait, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalln("Invalid REGION_FALLBACK_WAIT:", err)
+		}
+		SetRegionFallbackWait(wait)
+	}
+}
+
+// Change how long players wait for a match in their closest region
+func SetRegionFallbackWait(wait time.Duration) {
+	regionFallbackWait.Store(int64(wait))
+}
+
+// Get all regions in the order they should be tried in (closest first)
+func (rp RegionPreference) Regions() []string {
+	regions := []string{}
+	if rp.Region != "" {
+		regions = append(regions, rp.Region)
+	}
+
+	byPing := slices.SortedFunc(maps.Keys(rp.Pings), func(a, b string) int {
+		return rp.Pings[a] - rp.Pings[b]
+	})
+	for _, region := range byPing {
+		if !slices.Contains(regions, region) {
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+// Helper function for only keeping the candidates in the closest region that has any (all of them after waiting long enough)
+func filterByRegion(request SelectionRequest, candidates []MatchCandidate) []MatchCandidate {
+	if len(request.Regions) == 0 {
+		return candidates
+	}
+
+	for i, region := range request.Regions {
+
+		// Only the closest region is good enough until the player waited for some time
+		if i > 0 && request.Waited < time.Duration(regionFallbackWait.Load()) {
+			return []MatchCandidate{}
+		}
+
+		inRegion := slices.DeleteFunc(slices.Clone(candidates), func(c MatchCandidate) bool {
+			return c.Region != region
+		})
+		if len(inRegion) > 0 {
+			return inRegion
+		}
+	}
+
+	// Anywhere is better than nowhere after waiting long enough
+	if request.Waited < time.Duration(regionFallbackWait.Load()) {
+		return []MatchCandidate{}
+	}
+	return candidates
+}
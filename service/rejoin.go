@@ -0,0 +1,147 @@
+package service
+
+import (
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/ristretto/v2"
+)
+
+// How long players can get back into their match after disconnecting (unless the game uses another window)
+const DefaultRejoinWindow = 2 * time.Minute
+
+// Where a player was before they disconnected
+type rejoinEntry struct {
+	Game   string
+	Server int
+	Match  int
+}
+
+// Account -> rejoinEntry (only kept for as long as the rejoin window of the game is open)
+var rejoinCache *ristretto.Cache[string, rejoinEntry]
+
+func init() {
+	var err error
+	rejoinCache, err = ristretto.NewCache(&ristretto.Config[string, rejoinEntry]{
+		MaxCost:     10_000,      // Maximum 10.000 stored items
+		NumCounters: 10_000 * 10, // 10x what we want to store
+		BufferItems: 64,          // Read description of field
+	})
+	if err != nil {
+		log.Fatalln("couldn't create cache:", err)
+	}
+}
+
+// Get how long players of the game can rejoin their match (0 or less when they can't)
+func (mr *MatchRegistry) RejoinWindow() time.Duration {
+	mr.Mutex.RLock()
+	defer mr.Mutex.RUnlock()
+	return mr.rejoinWindow
+}
+
+// Change how long players of the game can rejoin their match (negative to not let them rejoin at all)
+func (mr *MatchRegistry) setRejoinWindow(window time.Duration) {
+	mr.Mutex.Lock()
+	defer mr.Mutex.Unlock()
+	mr.rejoinWindow = window
+}
+
+// Remember where a player is right before they are removed, so they can get back into the match when they queue again
+func rememberForRejoin(account string) {
+	player, ok := getPlayer(account)
+	if !ok {
+		return
+	}
+	player.Mutex.RLock()
+	server, matchId, joined := player.Server, player.Match, player.Confirmed && !player.Spectator
+	player.Mutex.RUnlock()
+	if !joined {
+		return // Only players that actually joined have something to rejoin
+	}
+
+	match, ok := GetMatchFromServer(server, matchId)
+	if !ok {
+		return
+	}
+	mr, ok := GetMatchRegistry(match.Game)
+	if !ok {
+		return
+	}
+	window := mr.RejoinWindow()
+	if window <= 0 {
+		return
+	}
+
+	rejoinCache.SetWithTTL(account, rejoinEntry{
+		Game:   match.Game,
+		Server: server,
+		Match:  matchId,
+	}, 1, window)
+	rejoinCache.Wait()
+}
+
+// Give a player that disconnected a new token for the match they were in (false when there is nothing to rejoin)
+// Players the server didn't report as disconnected yet can't rejoin, they might still be connected.
+func rejoinMatch(game string, account string) (QueueStatus, bool) {
+	entry, ok := rejoinCache.Get(account)
+	if !ok || entry.Game != game {
+		return QueueStatus{}, false
+	}
+	match, ok := GetMatchFromServer(entry.Server, entry.Match)
+	if !ok {
+		return QueueStatus{}, false
+	}
+
+	// Take the slot back (in case nobody else got it in the meantime)
+	match.Mutex.Lock()
+	if match.State == MatchStateEnd || len(match.TokenStore) == 0 || len(match.Players) >= match.capacityNoMutex() {
+		match.Mutex.Unlock()
+		return QueueStatus{}, false
+	}
+	token := match.TokenStore[0]
+	match.TokenStore = slices.Delete(match.TokenStore, 0, 1)
+	match.Players = append(match.Players, account)
+	match.Mutex.Unlock()
+
+	rejoinCache.Del(account)
+	player := &PlayerInfo{
+		Mutex:   &sync.RWMutex{},
+		Account: account,
+		Server:  entry.Server,
+		Match:   entry.Match,
+		Token:   token,
+	}
+	if !addPlayer(entry.Server, account, player, true) {
+		releaseReservations(match, nil, []string{account}, []string{token})
+		return QueueStatus{}, false
+	}
+	return rejoinStatus(player, token), true
+}
+
+// Helper function for telling the server about the rejoin and creating the status for the player
+func rejoinStatus(player *PlayerInfo, token string) QueueStatus {
+	player.Mutex.RLock()
+	account, server, match := player.Account, player.Server, player.Match
+	player.Mutex.RUnlock()
+
+	publishEvent(server, Event{
+		Type: EventPlayerReserved,
+		Data: PlayerEvent{
+			Player: account,
+			Match:  match,
+			Token:  token,
+			Rejoin: true,
+		},
+	})
+
+	region, _ := GetServerRegion(server)
+	return QueueStatus{
+		Assigned: true,
+		Server:   server,
+		Region:   region,
+		Tokens:   map[string]string{account: token},
+		Rejoin:   true,
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"log"
+	"slices"
+	"sync"
+)
+
+// The accounts a server says are connected to one of its matches (spectators aren't part of it)
+type MatchRoster struct {
+	Match   int      `json:"match"`
+	Players []string `json:"players"`
+}
+
+// Everything that was changed to make the matchmaker agree with a server
+type RosterCorrections struct {
+	Added   []string `json:"added"`   // Connected players the matchmaker didn't know about (or only had a reservation for)
+	Removed []string `json:"removed"` // Players the matchmaker thought were connected, but aren't anymore
+}
+
+// Make the players of a server's matches agree with what the server says is connected (matches that aren't in the list are left alone)
+func ReconcileRoster(server int, rosters []MatchRoster) RosterCorrections {
+	corrections := RosterCorrections{
+		Added:   []string{},
+		Removed: []string{},
+	}
+	for _, roster := range rosters {
+		match, ok := GetMatchFromServer(server, roster.Match)
+		if !ok {
+			log.Println("Roster of server", server, "contains match", roster.Match, "which the matchmaker doesn't know about")
+			continue
+		}
+		reconcileMatch(match, roster.Players, &corrections)
+	}
+	return corrections
+}
+
+// Helper function for reconciling the players of a single match
+func reconcileMatch(match *Match, connected []string, corrections *RosterCorrections) {
+	match.Mutex.RLock()
+	players := slices.Clone(match.Players)
+	server, id := match.Server, match.ID
+	match.Mutex.RUnlock()
+
+	// Remove everyone that joined, but isn't connected anymore (reservations still have time to join)
+	changed := false
+	for _, account := range players {
+		if slices.Contains(connected, account) {
+			continue
+		}
+
+		player, ok := getPlayer(account)
+		if !ok {
+			continue
+		}
+		player.Mutex.RLock()
+		ghost := player.Confirmed && !player.Spectator && player.Server == server && player.Match == id
+		player.Mutex.RUnlock()
+		if !ghost {
+			continue
+		}
+
+		rememberForRejoin(account) // Most likely a disconnect the server didn't report
+		deletePlayer(account, &CachedPlayer{Id: account, Server: server}, false)
+		log.Println("Roster of server", server, "match", id, ": removed", account, "(not connected anymore)")
+		corrections.Removed = append(corrections.Removed, account)
+		changed = true
+	}
+
+	// Add everyone that is connected, but the matchmaker doesn't know about
+	for _, account := range connected {
+		reason, ok := addConnectedPlayer(match, account)
+		if !ok {
+			continue
+		}
+
+		log.Println("Roster of server", server, "match", id, ": added", account, "("+reason+")")
+		corrections.Added = append(corrections.Added, account)
+		changed = true
+	}
+
+	if changed {
+		checkMatchReadiness(match)
+	}
+}
+
+// Helper function for making sure a connected player is confirmed in a match (returns why they had to be added, false when they were already there)
+func addConnectedPlayer(match *Match, account string) (string, bool) {
+	match.Mutex.RLock()
+	server, id := match.Server, match.ID
+	match.Mutex.RUnlock()
+
+	reason := "unknown to the matchmaker"
+	if player, ok := getPlayer(account); ok {
+		player.Mutex.Lock()
+		here := player.Server == server && player.Match == id
+		switch {
+		case here && (player.Confirmed || player.Spectator):
+			player.Mutex.Unlock()
+			return "", false
+		case here:
+
+			// The server didn't confirm the reservation, but the player is clearly there
+			player.Confirmed = true
+			player.Mutex.Unlock()
+			match.Mutex.Lock()
+			match.addParticipantNoMutex(account)
+			match.Mutex.Unlock()
+			addPlayer(server, account, player, false)
+			return "reservation wasn't confirmed", true
+		}
+		player.Mutex.Unlock()
+
+		// The matchmaker thinks they're somewhere else, but the server knows better
+		DeletePlayer(account, nil)
+		reason = "was somewhere else"
+	}
+	CancelQueue(account)
+
+	// Take one of the tokens (when there are none left, the player just doesn't have one)
+	match.Mutex.Lock()
+	token := ""
+	if !slices.Contains(match.Players, account) {
+		if len(match.TokenStore) > 0 {
+			token = match.TokenStore[0]
+			match.TokenStore = slices.Delete(match.TokenStore, 0, 1)
+		}
+		match.Players = append(match.Players, account)
+	}
+	match.addParticipantNoMutex(account)
+	match.Mutex.Unlock()
+
+	addPlayer(server, account, &PlayerInfo{
+		Mutex:     &sync.RWMutex{},
+		Account:   account,
+		Server:    server,
+		Match:     id,
+		Token:     token,
+		Confirmed: true,
+	}, false)
+	return reason, true
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Built-in strategies for choosing the match players are put into
+const (
+	SelectorFillFullest = "fill_fullest" // Fill up the match with the most players first (the default)
+	SelectorRoundRobin  = "round_robin"  // Take turns between all servers hosting the game
+	SelectorLeastLoaded = "least_loaded" // Use the server with the least players on it
+	SelectorRandom      = "random"       // Just pick any match
+	SelectorSkill       = "skill"        // Prefer matches where the average rating is close to the player's rating
+)
+
+// Who is looking for a match
+type SelectionRequest struct {
+	Game     string
+	Accounts []string      // Everyone that needs a slot in the same match
+	Regions  []string      // Regions the players would like to play in (closest first, see regions.go)
+	Waited   time.Duration // How long they've been waiting in the queue already
+}
+
+// A match that has enough slots for everyone in a selection request
+type MatchCandidate struct {
+	Match      *Match
+	Server     int
+	Region     string   // Region of the server
+	Tags       []string // Tags of the server
+	Players    []string // Accounts in the match when it was collected
+	ServerLoad int      // Players (and reservations) on the whole server
+}
+
+// Strategy for choosing a match (one instance is created for every game, so it can keep state)
+type MatchSelector interface {
+
+	// Pick one of the candidates (nil in case none of them should be used)
+	Select(request SelectionRequest, candidates []MatchCandidate) *Match
+}
+
+// Name -> func() MatchSelector
+var selectorFactories = &sync.Map{}
+
+func init() {
+	RegisterMatchSelector(SelectorFillFullest, func() MatchSelector { return &FillFullestSelector{} })
+	RegisterMatchSelector(SelectorRoundRobin, func() MatchSelector { return &RoundRobinSelector{} })
+	RegisterMatchSelector(SelectorLeastLoaded, func() MatchSelector { return &LeastLoadedSelector{} })
+	RegisterMatchSelector(SelectorRandom, func() MatchSelector { return &RandomSelector{} })
+	RegisterMatchSelector(SelectorSkill, func() MatchSelector { return &SkillSelector{} })
+}
+
+// Make a selector available to game servers (replaces the one registered with the same name)
+func RegisterMatchSelector(name string, factory func() MatchSelector) {
+	selectorFactories.Store(name, factory)
+}
+
+// Create a new instance of a selector (false if there is none with the name)
+func NewMatchSelector(name string) (MatchSelector, bool) {
+	obj, ok := selectorFactories.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return obj.(func() MatchSelector)(), true
+}
+
+// Check if a selector with the name exists
+func IsValidMatchSelector(name string) bool {
+	_, ok := selectorFactories.Load(name)
+	return ok
+}
+
+// Helper function for getting the candidate with the most players out of a list (ties go to the one that came first)
+func fullestCandidate(candidates []MatchCandidate) *Match {
+	var best *Match
+	currentSize := -1
+	for _, candidate := range candidates {
+		if currentSize < len(candidate.Players) {
+			best = candidate.Match
+			currentSize = len(candidate.Players)
+		}
+	}
+	return best
+}
+
+type FillFullestSelector struct{}
+
+func (*FillFullestSelector) Select(request SelectionRequest, candidates []MatchCandidate) *Match {
+	return fullestCandidate(candidates)
+}
+
+type RoundRobinSelector struct {
+	mutex      sync.Mutex
+	lastServer int
+}
+
+func (rs *RoundRobinSelector) Select(request SelectionRequest, candidates []MatchCandidate) *Match {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	// Take the next server after the last one (or start from the beginning again)
+	servers := []int{}
+	for _, candidate := range candidates {
+		servers = append(servers, candidate.Server)
+	}
+	slices.Sort(servers)
+	next := servers[0]
+	if i := slices.IndexFunc(servers, func(server int) bool { return server > rs.lastServer }); i >= 0 {
+		next = servers[i]
+	}
+	rs.lastServer = next
+
+	return fullestCandidate(slices.DeleteFunc(slices.Clone(candidates), func(c MatchCandidate) bool {
+		return c.Server != next
+	}))
+}
+
+type LeastLoadedSelector struct{}
+
+func (*LeastLoadedSelector) Select(request SelectionRequest, candidates []MatchCandidate) *Match {
+	var best *MatchCandidate
+	for i, candidate := range candidates {
+		if best == nil || candidate.ServerLoad < best.ServerLoad ||
+			(candidate.ServerLoad == best.ServerLoad && len(candidate.Players) > len(best.Players)) {
+			best = &candidates[i]
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return best.Match
+}
+
+type RandomSelector struct{}
+
+func (*RandomSelector) Select(request SelectionRequest, candidates []MatchCandidate) *Match {
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[rand.IntN(len(candidates))].Match
+}
+
+// Picks the match with the average rating closest to the players (see ratings.go)
+type SkillSelector struct{}
+
+func (*SkillSelector) Select(request SelectionRequest, candidates []MatchCandidate) *Match {
+	rating, _ := averageRating(request.Game, request.Accounts)
+	window := skillWindow(request.Waited)
+
+	var best *Match
+	bestDistance, bestSize := math.Inf(1), -1
+	for _, candidate := range candidates {
+
+		// Empty matches fit everyone, but matches with players close to the rating are preferred
+		distance := window
+		if average, ok := averageRating(request.Game, candidate.Players); ok {
+			distance = math.Abs(average - rating)
+		}
+		if distance > window {
+			continue
+		}
+
+		if distance < bestDistance || (distance == bestDistance && len(candidate.Players) > bestSize) {
+			best = candidate.Match
+			bestDistance, bestSize = distance, len(candidate.Players)
+		}
+	}
+	return best
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"log"
+	"slices"
 	"sync"
 	"time"
 
@@ -9,13 +10,26 @@ import (
 )
 
 const RecommendedRenewInterval = 20 * time.Second
-const ServerTTL = 60 * time.Second
+const (
+	ServerTTL         = 60 * time.Second
+	LostServerTimeout = 10 * time.Minute // How long matches in progress are kept after their server was evicted (it can renew until then)
+)
 
 type ServerInfo struct {
 	Mutex   *sync.RWMutex // Just for the general data on the server (IP, etc.)
 	TokenId int           // Also used
 	IP      string
 	Port    int
+	Region  string   // Where the server is hosted (e.g. eu or na, empty when unknown)
+	Tags    []string // Anything else custom selectors might want to know about the server
+
+	State        string    // Where the server is in its lifecycle (see lifecycle.go)
+	plannedDrain bool      // Whether the capacity planner drained the server (only those are brought back by it)
+	Registered   time.Time // When the server registered (the planner doesn't drain servers that just did, see capacity.go)
+	LastRenew    time.Time // When the server registered or renewed for the last time
+	Secret       string    // What the server uses to authenticate itself (see auth.go)
+	Audience     string    // What join tickets for the server are issued for (new with every registration, see tickets.go)
+	lost         bool      // Evicted while matches were in progress, only those are kept until it renews again (see lifecycle.go)
 
 	Matches *sync.Map // Match id -> *Match
 	Players *sync.Map // Player id -> *PlayerInfo
@@ -23,6 +37,9 @@ type ServerInfo struct {
 
 var serverCache *ristretto.Cache[int, *ServerInfo]
 
+// Server id -> *ServerInfo (ristretto can't be iterated, this is kept in sync with the cache)
+var serverList = &sync.Map{}
+
 func init() {
 	var err error
 	serverCache, err = ristretto.NewCache(&ristretto.Config[int, *ServerInfo]{
@@ -31,35 +48,18 @@ func init() {
 		BufferItems: 64,          // Read description of field
 
 		OnEvict: func(item *ristretto.Item[*ServerInfo]) {
-			log.Println("Server", item.Value.IP, "disconnected.")
-
-			// Cleanup server (in goroutine to make sure it doesn't block anything in ristretto)
-			go func() {
-				MarkTokenAsUnused(item.Value.TokenId)
-
-				// Delete all players
-				item.Value.Players.Range(func(key, value any) bool {
-					p := value.(*PlayerInfo)
-
-					p.Mutex.RLock()
-					defer p.Mutex.RUnlock()
-
-					DeletePlayer(p.Account, nil)
-					return true
-				})
-				item.Value.Players.Clear()
-
-				// Mark all matches as ended (in case they are in some game they will get cleaned and no-one will be able to join)
-				item.Value.Matches.Range(func(key, value any) bool {
-					m := value.(*Match)
-
-					m.Mutex.Lock()
-					defer m.Mutex.Unlock()
-					m.State = MatchStateEnd
-					return true
-				})
-				item.Value.Matches.Clear()
-			}()
+
+			// Only servers that actually stopped renewing are evicted (the ones removed by clearing the cache are just cleaned up)
+			if item.Expiration.IsZero() || !time.Now().After(item.Expiration) {
+				cleanupServer(item.Value)
+				return
+			}
+			serverEvictions.Inc()
+
+			// In a goroutine since the server might be put back into the cache
+			cleanupGroup.Go(func() {
+				evictServer(item.Value)
+			})
 		},
 	})
 	if err != nil {
@@ -67,27 +67,127 @@ func init() {
 	}
 }
 
+// Remove everything on a server after it has been removed from the cache
+func cleanupServer(server *ServerInfo) {
+
+	// Make sure the same server isn't cleaned up twice (and a server that registered with the same id since is left alone)
+	if !serverList.CompareAndDelete(server.TokenId, server) {
+		return
+	}
+	log.Println("Server", server.IP, "disconnected.")
+
+	// Cleanup server (in goroutine to make sure it doesn't block anything in ristretto)
+	cleanupGroup.Go(func() {
+		MarkTokenAsUnused(server.TokenId)
+
+		// Delete all players (the server is already gone from the cache, so DeletePlayer can't be used here)
+		server.Players.Range(func(key, value any) bool {
+			p := value.(*PlayerInfo)
+
+			cached, ok := PlayerCache.Get(p.Account)
+			if !ok || cached.Server != server.TokenId {
+				return true
+			}
+
+			// Make sure the player isn't on a server that registered with the same id since
+			if current, ok := getPlayerFromCached(cached); ok && current != p {
+				return true
+			}
+
+			PlayerCache.Del(p.Account)
+			return true
+		})
+		PlayerCache.Wait()
+		server.Players.Clear()
+
+		// Mark all matches as ended (they are removed from their games right away so no-one will be able to join, the server isn't told since it's gone)
+		server.Matches.Range(func(key, value any) bool {
+			m := value.(*Match)
+
+			m.Mutex.Lock()
+			m.State = MatchStateEnd
+			m.stopForceStartNoMutex()
+			m.Mutex.Unlock()
+
+			if mr, ok := GetMatchRegistry(m.Game); ok {
+				mr.reindex(m)
+			}
+			return true
+		})
+		server.Matches.Clear()
+
+		// Make sure nobody keeps listening for events of a server that's gone
+		if _, ok := serverList.Load(server.TokenId); !ok {
+			closeEventStreams(server.TokenId)
+		}
+	})
+}
+
 func CreateServer(id int, ip string, port int) bool {
-	overwritten := serverCache.SetWithTTL(id, &ServerInfo{
-		Mutex:   &sync.RWMutex{},
-		TokenId: id,
-		IP:      ip,
-		Port:    port,
-		Players: &sync.Map{},
-		Matches: &sync.Map{},
-	}, 1, ServerTTL)
+	return CreateServerInRegion(id, ip, port, "", nil)
+}
+
+// Register a server that's hosted in a region (the region and tags are optional, false when the cache didn't take the server)
+func CreateServerInRegion(id int, ip string, port int, region string, tags []string) bool {
+	now := time.Now()
+	info := &ServerInfo{
+		Mutex:      &sync.RWMutex{},
+		TokenId:    id,
+		IP:         ip,
+		Port:       port,
+		Region:     region,
+		Tags:       slices.Clone(tags),
+		State:      ServerStateReady,
+		Registered: now,
+		LastRenew:  now,
+		Audience:   newTicketAudience(id),
+		Players:    &sync.Map{},
+		Matches:    &sync.Map{},
+	}
+
+	// Ristretto can drop the item, the server would only be in the list then
+	if !serverCache.SetWithTTL(id, info, 1, ServerTTL) {
+		return false
+	}
+	serverList.Store(id, info)
 
 	serverCache.Wait()
-	return overwritten
+	return true
 }
 
 func RefreshServer(id int) {
 	if item, ok := serverCache.Get(id); ok {
+		item.Mutex.Lock()
+		item.LastRenew = time.Now()
+		if item.lost {
+			item.lost = false
+			log.Println("Server", item.IP, "came back.")
+		}
+		item.Mutex.Unlock()
+
 		serverCache.SetWithTTL(id, item, 1, ServerTTL)
 		serverCache.Wait()
 	}
 }
 
+// Create a new secret for a server (the old one stops working)
+func IssueServerSecret(id int) (string, bool) {
+	server, ok := serverCache.Get(id)
+	if !ok {
+		return "", false
+	}
+
+	server.Mutex.Lock()
+	defer server.Mutex.Unlock()
+	server.Secret = newServerSecret(id)
+	return server.Secret, true
+}
+
+// Get how long a server has left to renew before it's evicted
+func ServerTTLLeft(id int) (time.Duration, bool) {
+	return serverCache.GetTTL(id)
+}
+
 // Get a server's ip and port
 func GetServerDetails(id int) (ip string, port int, ok bool) {
 	server, ok := serverCache.Get(id)
@@ -99,3 +199,22 @@ func GetServerDetails(id int) (ip string, port int, ok bool) {
 	defer server.Mutex.RUnlock()
 	return server.IP, server.Port, true
 }
+
+// Get the region of a server (empty when the server didn't say where it's hosted)
+func GetServerRegion(id int) (string, bool) {
+	server, ok := serverCache.Get(id)
+	if !ok {
+		return "", false
+	}
+
+	server.Mutex.RLock()
+	defer server.Mutex.RUnlock()
+	return server.Region, true
+}
+
+// Call a function for every server that is currently registered (return false to stop)
+func rangeServers(f func(id int, server *ServerInfo) bool) {
+	serverList.Range(func(key, value any) bool {
+		return f(key.(int), value.(*ServerInfo))
+	})
+}
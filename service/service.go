@@ -1,8 +1,22 @@
 package service
 
+import "sync"
+
+// Tracks the cleanup goroutines started when something is evicted from one of the caches
+var cleanupGroup = &sync.WaitGroup{}
+
 func ResetAll() {
 	PlayerCache.Clear()
+	queueCache.Clear()
+	rejoinCache.Clear()
 	serverCache.Clear()
+
+	// Make sure nothing that was just evicted is still being cleaned up
+	cleanupGroup.Wait()
+
+	serverList.Clear()
 	gameCache.Clear()
+	joinCodes.Clear()
 	tokensMap.Clear()
+	clearRatings()
 }
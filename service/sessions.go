@@ -0,0 +1,127 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/util"
+)
+
+const (
+	GameSessionRefreshMargin = 10 * time.Minute // Game sessions are replaced this long before they expire
+	DefaultSessionLifetime   = time.Hour        // Used when the session endpoint doesn't tell us when a session expires
+)
+
+type GameSession struct {
+	SessionToken  string    `json:"session_token"`
+	IdentityToken string    `json:"identity_token"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+type GameSessionRequest struct {
+	UUID string `json:"uuid"`
+}
+
+// What the session endpoint returns
+type GameSessionResponse struct {
+	SessionToken  string    `json:"sessionToken"`
+	IdentityToken string    `json:"identityToken"`
+	ExpiresAt     time.Time `json:"expiresAt"`
+}
+
+// Get the game session endpoint (HYTALE_SESSION_URL can be set to use a local stand-in instead)
+func GetGameSessionURL() string {
+	return hytaleEndpoint("HYTALE_SESSION_URL", GameSessionURL)
+}
+
+// Get the game session of a token (a new one is created when there is none or it expires soon)
+func GetGameSession(id int) (GameSession, error) {
+	obj, ok := tokensMap.Load(id)
+	if !ok {
+		return GameSession{}, errors.New("token doesn't exist")
+	}
+	info := obj.(*TokenInfo)
+
+	info.Mutex.Lock()
+	session := info.Session
+	info.Mutex.Unlock()
+
+	if session != nil && time.Until(session.ExpiresAt) > GameSessionRefreshMargin {
+		return *session, nil
+	}
+	return CreateGameSession(id)
+}
+
+// Create a new game session using the access token of a token and cache it
+func CreateGameSession(id int) (GameSession, error) {
+	obj, ok := tokensMap.Load(id)
+	if !ok {
+		return GameSession{}, errors.New("token doesn't exist")
+	}
+	info := obj.(*TokenInfo)
+
+	info.Mutex.Lock()
+	accessToken := info.Token.AccessToken
+	uuid := info.Token.UUID
+	info.Mutex.Unlock()
+
+	resp, err := util.Post[GameSessionResponse](GetGameSessionURL(), GameSessionRequest{
+		UUID: uuid,
+	}, util.Headers{
+		"Authorization": "Bearer " + accessToken,
+	})
+	if err != nil {
+		return GameSession{}, err
+	}
+	if resp.SessionToken == "" || resp.IdentityToken == "" {
+		return GameSession{}, errors.New("no session returned")
+	}
+
+	session := GameSession{
+		SessionToken:  resp.SessionToken,
+		IdentityToken: resp.IdentityToken,
+		ExpiresAt:     resp.ExpiresAt,
+	}
+	if session.ExpiresAt.IsZero() {
+		session.ExpiresAt = time.Now().Add(DefaultSessionLifetime)
+	}
+
+	info.Mutex.Lock()
+	info.Session = &session
+	info.Mutex.Unlock()
+
+	return session, nil
+}
+
+// Replace all game sessions of used tokens that expire soon (returns how many were replaced)
+func RefreshExpiringSessions() int {
+	expiring := []int{}
+	tokensMap.Range(func(key, value any) bool {
+		info := value.(*TokenInfo)
+		info.Mutex.Lock()
+		defer info.Mutex.Unlock()
+
+		if info.Used && info.Session != nil && time.Until(info.Session.ExpiresAt) < GameSessionRefreshMargin {
+			expiring = append(expiring, info.Id)
+		}
+		return true
+	})
+
+	refreshed := 0
+	for _, id := range expiring {
+		session, err := CreateGameSession(id)
+		if err != nil {
+			log.Println("Couldn't refresh game session of token", id, ":", err)
+			continue
+		}
+		refreshed++
+
+		// Tell the server using the token about the new session (server ids are the same as token ids)
+		publishEvent(id, Event{
+			Type: EventGameSessionRefreshed,
+			Data: session,
+		})
+	}
+	return refreshed
+}
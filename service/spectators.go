@@ -0,0 +1,137 @@
+package service
+
+import (
+	"slices"
+	"sync"
+)
+
+// What a spectator wants to watch (only one of the ways to find a match should be set)
+type SpectateTarget struct {
+	Server int    // Together with the match to spectate a specific match
+	Match  int    // Unique by server
+	Game   string // Spectate the fullest running match of a game
+	Follow string // Spectate the match a player is currently in
+}
+
+// Check if a spectator can join the match (always lock the mutex before)
+func (m *Match) canBeSpectatedNoMutex() bool {
+	return (m.State == MatchStateAccepting || m.State == MatchStateFull) && len(m.SpectatorTokens) > 0
+}
+
+// Tries to add a spectator to the match (returns false if there is no spectator slot left)
+func (m *Match) AddSpectatorIfPossible(id string) (string, bool) {
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+
+	if !m.canBeSpectatedNoMutex() {
+		return "", false
+	}
+	m.Spectators = append(m.Spectators, id)
+
+	// Take the first token and remove it
+	token := m.SpectatorTokens[0]
+	m.SpectatorTokens = slices.Delete(m.SpectatorTokens, 0, 1)
+	return token, true
+}
+
+// Reserve a spectator slot for an account (returns the token, server and match id if it worked)
+func CreateSpectatorIfPossible(target SpectateTarget, account string) (string, int, int, bool) {
+	if _, ok := PlayerCache.Get(account); ok {
+		return "", 0, 0, false
+	}
+
+	match, ok := findMatchToSpectate(target)
+	if !ok {
+		return "", 0, 0, false
+	}
+
+	token, ok := match.AddSpectatorIfPossible(account)
+	if !ok {
+		return "", 0, 0, false
+	}
+
+	match.Mutex.RLock()
+	defer match.Mutex.RUnlock()
+
+	player := &PlayerInfo{
+		Mutex:     &sync.RWMutex{},
+		Account:   account,
+		Server:    match.Server,
+		Match:     match.ID,
+		Token:     token,
+		Confirmed: false,
+		Spectator: true,
+	}
+	if !addPlayer(match.Server, account, player, true) {
+		return "", 0, 0, false
+	}
+
+	publishEvent(match.Server, Event{
+		Type: EventPlayerReserved,
+		Data: PlayerEvent{
+			Player:    account,
+			Match:     match.ID,
+			Token:     token,
+			Spectator: true,
+		},
+	})
+	return token, match.Server, match.ID, true
+}
+
+// Helper function for finding the match a spectator should join
+func findMatchToSpectate(target SpectateTarget) (*Match, bool) {
+	switch {
+	case target.Follow != "":
+		followed, ok := getPlayer(target.Follow)
+		if !ok {
+			return nil, false
+		}
+
+		followed.Mutex.RLock()
+		server, matchId := followed.Server, followed.Match
+		followed.Mutex.RUnlock()
+
+		// Nobody can follow someone into a private match
+		return getPublicMatch(server, matchId)
+
+	case target.Game != "":
+		mr, ok := GetMatchRegistry(target.Game)
+		if !ok {
+			return nil, false
+		}
+		match := mr.getMatchToSpectate()
+		return match, match != nil
+
+	default:
+		return getPublicMatch(target.Server, target.Match) // Private matches can only be joined with their code
+	}
+}
+
+// Helper function for getting a match that isn't private (false when it doesn't exist or is private)
+func getPublicMatch(server int, matchId int) (*Match, bool) {
+	match, ok := GetMatchFromServer(server, matchId)
+	if !ok {
+		return nil, false
+	}
+	match.Mutex.RLock()
+	defer match.Mutex.RUnlock()
+	return match, !match.Private
+}
+
+// Get the match with the most players that can still be spectated (nil if there isn't one)
+func (mr *MatchRegistry) getMatchToSpectate() *Match {
+	mr.Mutex.RLock()
+	defer mr.Mutex.RUnlock()
+
+	var best *Match
+	currentSize := -1
+	for _, match := range mr.available {
+		match.Mutex.RLock()
+		if match.canBeSpectatedNoMutex() && !match.Private && currentSize < len(match.Players) {
+			best = match
+			currentSize = len(match.Players)
+		}
+		match.Mutex.RUnlock()
+	}
+	return best
+}
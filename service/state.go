@@ -0,0 +1,397 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+const StateFileName = "state.json"
+const StateSnapshotInterval = 10 * time.Second
+
+// Restored servers have this long to renew before they are evicted
+const RestoreGraceWindow = 2 * ServerTTL
+
+// Backend for persisting the state of the network across restarts
+type StateStore interface {
+	Save(snapshot StateSnapshot) error
+	Load() (StateSnapshot, bool, error) // Returns false when nothing has been saved yet
+}
+
+type StateSnapshot struct {
+	Time      time.Time         `json:"time"`
+	Servers   []ServerSnapshot  `json:"servers"`
+	Selectors map[string]string `json:"selectors,omitempty"` // Game -> selector for choosing matches
+
+	RejoinWindows map[string]int `json:"rejoin_windows,omitempty"` // Game -> seconds players can rejoin (only games not using the default)
+}
+
+type ServerSnapshot struct {
+	ID      int              `json:"id"` // Also the id of the token the server is using
+	IP      string           `json:"ip"`
+	Port    int              `json:"port"`
+	Region  string           `json:"region,omitempty"`
+	Tags    []string         `json:"tags,omitempty"`
+	State   string           `json:"state"`
+	Planned bool             `json:"planned,omitempty"` // Whether the capacity planner drained the server
+	Secret  string           `json:"secret"`
+	Matches []MatchSnapshot  `json:"matches"`
+	Players []PlayerSnapshot `json:"players"` // Only confirmed players (reservations aren't restored)
+
+	Registered time.Time `json:"registered"`
+	TokenUUID  string    `json:"token_uuid,omitempty"` // For making sure the token with the id is still the one the server uses
+	Audience   string    `json:"audience"`
+}
+
+type MatchSnapshot struct {
+	ID         int      `json:"id"`
+	Game       string   `json:"game"`
+	State      string   `json:"state"`
+	Players    []string `json:"players"`
+	TokenStore []string `json:"token_store"`
+
+	Spectators      []string `json:"spectators"`
+	SpectatorTokens []string `json:"spectator_tokens"`
+
+	MinPlayers      int           `json:"min_players,omitempty"`
+	MaxPlayers      int           `json:"max_players,omitempty"`
+	ForceStartAfter time.Duration `json:"force_start_after,omitempty"`
+
+	Reported     bool     `json:"reported,omitempty"` // Whether the result has been reported already
+	Participants []string `json:"participants,omitempty"`
+
+	JoinCode  string   `json:"join_code,omitempty"` // Only set for private matches
+	Password  string   `json:"password,omitempty"`
+	Allowlist []string `json:"allowlist,omitempty"`
+}
+
+type PlayerSnapshot struct {
+	Account string `json:"account"`
+	Match   int    `json:"match"`
+	Token   string `json:"token"`
+
+	Spectator bool `json:"spectator,omitempty"`
+}
+
+// The store currently used (nil if persistence is disabled)
+var stateStore StateStore
+var stateMutex = &sync.Mutex{}
+
+// Choose the state store based on the environment, restore the last snapshot and start taking new ones
+func SetupState() {
+	switch os.Getenv("STATE_STORE") {
+	case "none":
+		log.Println("State persistence is disabled.")
+		return
+	case "", "file":
+		SetStateStore(NewFileStateStore(path.Join(os.Getenv("TOKEN_FILE_LOCATION"), StateFileName)))
+	default:
+		log.Fatalln("Unknown state store:", os.Getenv("STATE_STORE"))
+	}
+
+	snapshot, ok, err := stateStore.Load()
+	if err != nil {
+		log.Fatalln("Couldn't load state:", err)
+	}
+	if ok {
+		RestoreSnapshot(snapshot)
+		log.Println("Restored", len(snapshot.Servers), "servers from the snapshot taken at", snapshot.Time.Format(time.RFC3339))
+	}
+
+	go func() {
+		for {
+			time.Sleep(StateSnapshotInterval)
+			if err := SaveState(); err != nil {
+				log.Println("Couldn't save state:", err)
+			}
+		}
+	}()
+}
+
+// Replace the store used for persisting the state
+func SetStateStore(store StateStore) {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+	stateStore = store
+}
+
+// Save a snapshot of the current state to the state store (does nothing when persistence is disabled)
+func SaveState() error {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	if stateStore == nil {
+		return nil
+	}
+	return stateStore.Save(TakeSnapshot())
+}
+
+// Create a snapshot of all servers, matches and confirmed players
+func TakeSnapshot() StateSnapshot {
+	snapshot := StateSnapshot{
+		Time:      time.Now(),
+		Servers:   []ServerSnapshot{},
+		Selectors: map[string]string{},
+
+		RejoinWindows: map[string]int{},
+	}
+
+	gameCache.Range(func(key, value any) bool {
+		mr := value.(*MatchRegistry)
+		if selector := mr.Selector(); selector != SelectorFillFullest {
+			snapshot.Selectors[mr.Game] = selector
+		}
+		if window := mr.RejoinWindow(); window != DefaultRejoinWindow {
+			snapshot.RejoinWindows[mr.Game] = int(window.Seconds())
+		}
+		return true
+	})
+
+	rangeServers(func(id int, server *ServerInfo) bool {
+		server.Mutex.RLock()
+		serverSnapshot := ServerSnapshot{
+			ID:      id,
+			IP:      server.IP,
+			Port:    server.Port,
+			Region:  server.Region,
+			Tags:    server.Tags,
+			State:   server.State,
+			Planned: server.plannedDrain,
+			Secret:  server.Secret,
+			Matches: []MatchSnapshot{},
+			Players: []PlayerSnapshot{},
+
+			Registered: server.Registered,
+			Audience:   server.Audience,
+		}
+		server.Mutex.RUnlock()
+		if token, ok := GetToken(id); ok {
+			serverSnapshot.TokenUUID = token.UUID
+		}
+
+		// Collect all players that actually joined
+		confirmed := map[string]bool{}
+		reserved := map[string]string{} // Account -> token of the players that didn't join yet
+		server.Players.Range(func(key, value any) bool {
+			player := value.(*PlayerInfo)
+
+			player.Mutex.RLock()
+			defer player.Mutex.RUnlock()
+			if !player.Confirmed {
+				reserved[player.Account] = player.Token
+				return true
+			}
+
+			confirmed[player.Account] = true
+			serverSnapshot.Players = append(serverSnapshot.Players, PlayerSnapshot{
+				Account:   player.Account,
+				Match:     player.Match,
+				Token:     player.Token,
+				Spectator: player.Spectator,
+			})
+			return true
+		})
+
+		// Save the matches (tokens of reservations are put back into the token store)
+		server.Matches.Range(func(key, value any) bool {
+			match := value.(*Match)
+
+			match.Mutex.RLock()
+			defer match.Mutex.RUnlock()
+			if match.State == MatchStateEnd {
+				return true
+			}
+
+			matchSnapshot := MatchSnapshot{
+				ID:         match.ID,
+				Game:       match.Game,
+				State:      match.State,
+				Players:    []string{},
+				TokenStore: append([]string{}, match.TokenStore...),
+
+				Spectators:      []string{},
+				SpectatorTokens: append([]string{}, match.SpectatorTokens...),
+
+				MinPlayers:      match.MinPlayers,
+				MaxPlayers:      match.MaxPlayers,
+				ForceStartAfter: match.ForceStartAfter,
+
+				Reported:     match.Reported,
+				Participants: match.Participants,
+
+				JoinCode:  match.JoinCode,
+				Password:  match.Password,
+				Allowlist: match.Allowlist,
+			}
+			for _, player := range match.Players {
+				if confirmed[player] {
+					matchSnapshot.Players = append(matchSnapshot.Players, player)
+				} else if token, ok := reserved[player]; ok {
+					matchSnapshot.TokenStore = append(matchSnapshot.TokenStore, token)
+				}
+			}
+			for _, spectator := range match.Spectators {
+				if confirmed[spectator] {
+					matchSnapshot.Spectators = append(matchSnapshot.Spectators, spectator)
+				} else if token, ok := reserved[spectator]; ok {
+					matchSnapshot.SpectatorTokens = append(matchSnapshot.SpectatorTokens, token)
+				}
+			}
+
+			serverSnapshot.Matches = append(serverSnapshot.Matches, matchSnapshot)
+			return true
+		})
+
+		snapshot.Servers = append(snapshot.Servers, serverSnapshot)
+		return true
+	})
+
+	return snapshot
+}
+
+// Put everything from a snapshot back into the caches (servers have RestoreGraceWindow to renew)
+func RestoreSnapshot(snapshot StateSnapshot) {
+	for game, selector := range snapshot.Selectors {
+		getOrCreateRegistry(game).setSelector(selector)
+	}
+	for game, window := range snapshot.RejoinWindows {
+		getOrCreateRegistry(game).setRejoinWindow(time.Duration(window) * time.Second)
+	}
+
+	restored := []*Match{}
+	for _, server := range snapshot.Servers {
+
+		// Servers are found by the id of their token, it has to still be the same token
+		if token, ok := GetToken(server.ID); server.TokenUUID != "" && (!ok || token.UUID != server.TokenUUID) {
+			log.Println("Couldn't restore server", server.ID, "(its token isn't in the pool anymore)")
+			continue
+		}
+
+		info := &ServerInfo{
+			Mutex:   &sync.RWMutex{},
+			TokenId: server.ID,
+			IP:      server.IP,
+			Port:    server.Port,
+			Region:  server.Region,
+			Tags:    server.Tags,
+			State:   server.State,
+			Secret:  server.Secret,
+			Players: &sync.Map{},
+
+			plannedDrain: server.Planned,
+
+			Registered: server.Registered,
+			LastRenew:  time.Now(), // The grace window starts now
+			Audience:   server.Audience,
+			Matches:    &sync.Map{},
+		}
+		if !serverCache.SetWithTTL(server.ID, info, 1, RestoreGraceWindow) {
+			log.Println("Couldn't restore server", server.ID, "(dropped by the cache)")
+			continue
+		}
+		serverList.Store(server.ID, info)
+		markTokenAsUsed(server.ID)
+
+		for _, m := range server.Matches {
+			match := &Match{
+				Mutex:      &sync.RWMutex{},
+				ID:         m.ID,
+				Server:     server.ID,
+				State:      m.State,
+				Game:       m.Game,
+				Players:    m.Players,
+				TokenStore: m.TokenStore,
+
+				Spectators:      m.Spectators,
+				SpectatorTokens: m.SpectatorTokens,
+
+				MinPlayers:      m.MinPlayers,
+				MaxPlayers:      m.MaxPlayers,
+				ForceStartAfter: m.ForceStartAfter,
+
+				Reported:     m.Reported,
+				Participants: m.Participants,
+
+				Private:   m.JoinCode != "",
+				JoinCode:  m.JoinCode,
+				Password:  m.Password,
+				Allowlist: m.Allowlist,
+			}
+			if match.Private {
+				joinCodes.Store(match.JoinCode, match) // Players keep using the same code
+			}
+			info.Matches.Store(m.ID, match)
+			addMatchToGame(m.Game, match)
+			restored = append(restored, match)
+		}
+
+		for _, p := range server.Players {
+			info.Players.Store(p.Account, &PlayerInfo{
+				Mutex:     &sync.RWMutex{},
+				Account:   p.Account,
+				Server:    server.ID,
+				Match:     p.Match,
+				Token:     p.Token,
+				Confirmed: true,
+				Spectator: p.Spectator,
+			})
+			PlayerCache.Set(p.Account, CachedPlayer{
+				Id:     p.Account,
+				Server: server.ID,
+			}, 1)
+		}
+	}
+
+	serverCache.Wait()
+	PlayerCache.Wait()
+
+	// The countdowns start again since the servers have to reconnect anyway
+	for _, match := range restored {
+		checkMatchReadiness(match)
+	}
+}
+
+// Stores snapshots as JSON in a file on disk
+type FileStateStore struct {
+	Path string
+}
+
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{
+		Path: path,
+	}
+}
+
+func (fs *FileStateStore) Save(snapshot StateSnapshot) error {
+	bytes, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first to make sure a crash can't leave a broken snapshot behind
+	tmp := fs.Path + ".tmp"
+	if err := os.WriteFile(tmp, bytes, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fs.Path)
+}
+
+func (fs *FileStateStore) Load() (StateSnapshot, bool, error) {
+	var snapshot StateSnapshot
+	content, err := os.ReadFile(fs.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return snapshot, false, nil
+	}
+	if err != nil {
+		return snapshot, false, err
+	}
+
+	if err := json.Unmarshal(content, &snapshot); err != nil {
+		return snapshot, false, err
+	}
+	return snapshot, true, nil
+}
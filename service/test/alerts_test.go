@@ -0,0 +1,174 @@
+package service_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlerts(t *testing.T) {
+	service.ResetAll()
+	t.Setenv("TOKEN_FILE_LOCATION", t.TempDir())
+
+	// Local sink for webhooks
+	webhooks := make(chan service.Alert, 10)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert service.Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		webhooks <- alert
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer webhook.Close()
+
+	// Local sink for mail
+	mails := make(chan string, 10)
+	host, port := startSMTPSink(t, mails)
+
+	service.ConfigureAlerts(service.AlertConfig{
+		LowTokenThreshold: 0.5,
+		Cooldown:          time.Hour,
+		Notifiers: []service.AlertNotifier{
+			&service.WebhookNotifier{URL: webhook.URL},
+			&service.MailNotifier{
+				Host: host,
+				Port: port,
+				From: "matchmaking@example.com",
+				To:   []string{"admin@example.com"},
+			},
+		},
+	})
+	defer service.ConfigureAlerts(service.AlertConfig{
+		LowTokenThreshold: service.DefaultLowTokenThreshold,
+		Cooldown:          service.DefaultAlertCooldown,
+	})
+
+	expectAlert := func(t *testing.T, alertType string) {
+		select {
+		case alert := <-webhooks:
+			assert.Equal(t, alertType, alert.Type)
+		case <-time.After(time.Second):
+			t.Fatal("no webhook received")
+		}
+
+		select {
+		case mail := <-mails:
+			assert.Contains(t, mail, "Subject: [Hytale Matchmaking] "+alertType)
+			assert.Contains(t, mail, "To: admin@example.com")
+		case <-time.After(time.Second):
+			t.Fatal("no mail received")
+		}
+	}
+
+	for range 4 {
+		service.AddToken(service.Token{
+			AccessToken: "access",
+			ExpiresAt:   time.Now().Add(time.Hour),
+		})
+	}
+
+	t.Run("enough free tokens", func(t *testing.T) {
+		service.GetFreeToken()
+		service.GetFreeToken()
+		assert.Equal(t, "", service.CheckTokenPool())
+	})
+
+	t.Run("low on tokens", func(t *testing.T) {
+		service.GetFreeToken()
+		assert.Equal(t, service.AlertLowTokens, service.CheckTokenPool())
+		expectAlert(t, service.AlertLowTokens)
+	})
+
+	t.Run("alerts aren't repeated during the cooldown", func(t *testing.T) {
+		assert.Equal(t, service.AlertLowTokens, service.CheckTokenPool())
+		select {
+		case <-webhooks:
+			t.Fatal("alert was sent again")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("no tokens left", func(t *testing.T) {
+		service.GetFreeToken()
+		assert.Equal(t, service.AlertNoTokens, service.CheckTokenPool())
+		expectAlert(t, service.AlertNoTokens)
+	})
+
+	t.Run("refused registration", func(t *testing.T) {
+		service.ReportRegistrationRefused("localhost", 3000)
+		expectAlert(t, service.AlertRegistrationRefused)
+	})
+}
+
+// Start a tiny SMTP server that puts every message it receives into a channel
+func startSMTPSink(t *testing.T, mails chan<- string) (string, int) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("couldn't start smtp sink:", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				write := func(line string) {
+					conn.Write([]byte(line + "\r\n"))
+				}
+
+				write("220 localhost ESMTP")
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+
+					command := strings.ToUpper(strings.TrimSpace(line))
+					switch {
+					case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+						write("250 localhost")
+					case command == "DATA":
+						write("354 go ahead")
+						data := []string{}
+						for {
+							line, err := reader.ReadString('\n')
+							if err != nil {
+								return
+							}
+							if strings.TrimRight(line, "\r\n") == "." {
+								break
+							}
+							data = append(data, strings.TrimRight(line, "\r\n"))
+						}
+						mails <- strings.Join(data, "\n")
+						write("250 ok")
+					case command == "QUIT":
+						write("221 bye")
+						return
+					default:
+						write("250 ok")
+					}
+				}
+			}()
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
@@ -0,0 +1,84 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapacityPlanning(t *testing.T) {
+	service.ResetAll()
+
+	const (
+		game    = "battle"
+		matchId = 1
+	)
+
+	// One server hosting a match and two idle servers (restored, so they registered long ago)
+	snapshot := service.StateSnapshot{}
+	for id := 1; id <= 3; id++ {
+		snapshot.Servers = append(snapshot.Servers, service.ServerSnapshot{
+			ID:    id,
+			IP:    "localhost",
+			Port:  3000 + id,
+			State: service.ServerStateReady,
+		})
+	}
+	snapshot.Servers[0].Matches = []service.MatchSnapshot{{
+		ID:         matchId,
+		Game:       game,
+		State:      service.MatchStateAccepting,
+		Players:    []string{},
+		TokenStore: []string{"a", "b", "c", "d"},
+	}}
+	service.RestoreSnapshot(snapshot)
+
+	for _, player := range []string{"p1", "p2"} {
+		_, _, ok := service.CreatePlayerIfPossible(game, player)
+		assert.True(t, ok)
+	}
+
+	t.Run("idle servers get drained", func(t *testing.T) {
+		plan := service.PlanCapacity()
+		assert.Equal(t, 3, plan.Demand)
+		assert.ElementsMatch(t, []int{2, 3}, plan.Drain)
+
+		start, ok := service.ShouldStartMatch(1)
+		assert.True(t, ok)
+		assert.True(t, start)
+
+		start, ok = service.ShouldStartMatch(2)
+		assert.True(t, ok)
+		assert.False(t, start)
+	})
+
+	t.Run("freshly registered servers can start their first match", func(t *testing.T) {
+		assert.True(t, service.CreateServer(4, "localhost", 3004))
+
+		plan := service.PlanCapacity()
+		assert.NotContains(t, plan.Drain, 4)
+
+		start, ok := service.ShouldStartMatch(4)
+		assert.True(t, ok)
+		assert.True(t, start)
+		assert.True(t, service.EvictServer(4))
+	})
+
+	t.Run("draining servers are revived when capacity is needed", func(t *testing.T) {
+		for _, player := range []string{"p3", "p4"} {
+			_, _, ok := service.CreatePlayerIfPossible(game, player)
+			assert.True(t, ok)
+		}
+
+		plan := service.PlanCapacity()
+		assert.Equal(t, 5, plan.Demand)
+		assert.Equal(t, 1, len(plan.Revive))
+		assert.Empty(t, plan.Drain)
+	})
+
+	t.Run("unknown server can't start a match", func(t *testing.T) {
+		_, ok := service.ShouldStartMatch(67)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,98 @@
+package service_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGameSessions(t *testing.T) {
+	service.ResetAll()
+	t.Setenv("TOKEN_FILE_LOCATION", t.TempDir())
+
+	// Local stand-in for the Hytale session endpoint (the lifetime of new sessions can be changed)
+	var requests atomic.Int32
+	var lifetime atomic.Int64
+	lifetime.Store(int64(time.Hour))
+	sessions := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req service.GameSessionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UUID != "profile" || r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		requests.Add(1)
+		json.NewEncoder(w).Encode(service.GameSessionResponse{
+			SessionToken:  "session",
+			IdentityToken: "identity",
+			ExpiresAt:     time.Now().Add(time.Duration(lifetime.Load())),
+		})
+	}))
+	defer sessions.Close()
+	t.Setenv("HYTALE_SESSION_URL", sessions.URL)
+
+	id := service.AddToken(service.Token{
+		AccessToken: "access",
+		UUID:        "profile",
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	info, ok := service.GetFreeToken()
+	assert.True(t, ok)
+	assert.Equal(t, id, info.Id)
+
+	t.Run("session is created with the access token", func(t *testing.T) {
+		session, err := service.GetGameSession(id)
+		assert.Nil(t, err)
+		assert.Equal(t, "session", session.SessionToken)
+		assert.Equal(t, "identity", session.IdentityToken)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("session is cached", func(t *testing.T) {
+		_, err := service.GetGameSession(id)
+		assert.Nil(t, err)
+		assert.Equal(t, int32(1), requests.Load())
+		assert.Equal(t, 0, service.RefreshExpiringSessions())
+	})
+
+	t.Run("sessions that expire soon are replaced", func(t *testing.T) {
+		lifetime.Store(int64(time.Minute))
+		_, err := service.CreateGameSession(id)
+		assert.Nil(t, err)
+		assert.Equal(t, int32(2), requests.Load())
+
+		events, unsubscribe := service.SubscribeToEvents(id)
+		defer unsubscribe()
+
+		lifetime.Store(int64(time.Hour))
+		assert.Equal(t, 1, service.RefreshExpiringSessions())
+		assert.Equal(t, int32(3), requests.Load())
+
+		event := testing_util.WaitForEvent(t, events, service.EventGameSessionRefreshed)
+		session := event.Data.(service.GameSession)
+		assert.Greater(t, time.Until(session.ExpiresAt), 50*time.Minute)
+	})
+
+	t.Run("the next server using the token gets a new session", func(t *testing.T) {
+		service.MarkTokenAsUnused(id)
+		info, ok := service.GetFreeToken()
+		assert.True(t, ok)
+		assert.Equal(t, id, info.Id)
+
+		_, err := service.GetGameSession(id)
+		assert.Nil(t, err)
+		assert.Equal(t, int32(4), requests.Load())
+	})
+
+	t.Run("unknown tokens don't have a session", func(t *testing.T) {
+		_, err := service.GetGameSession(id + 100)
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,156 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerLifecycle(t *testing.T) {
+	service.ResetAll()
+
+	const game = "battle"
+
+	// Two servers that each host a match players can join
+	for id := 1; id <= 2; id++ {
+		assert.True(t, service.CreateServer(id, "localhost", 3000+id))
+		assert.True(t, service.AddMatch(id, service.MatchCreate{
+			ID:   1,
+			Game: game,
+		}, []string{"a", "b", "c", "d"}))
+		assert.True(t, service.SetMatchState(id, 1, service.MatchStateAccepting))
+	}
+
+	t.Run("servers start ready unless told otherwise", func(t *testing.T) {
+		state, ok := service.GetServerState(1)
+		assert.True(t, ok)
+		assert.Equal(t, service.ServerStateReady, state)
+
+		assert.True(t, service.CreateServer(3, "localhost", 3003))
+		assert.True(t, service.MarkServerStarting(3))
+		start, _ := service.ShouldStartMatch(3)
+		assert.False(t, start)
+
+		assert.True(t, service.SetServerState(3, service.ServerStateReady))
+		assert.False(t, service.SetServerState(3, service.ServerStateStarting))
+		assert.False(t, service.SetServerState(3, service.ServerStateStopped))
+		assert.True(t, service.EvictServer(3))
+	})
+
+	t.Run("draining servers don't get new players", func(t *testing.T) {
+		events, unsubscribe := service.SubscribeToEvents(1)
+		defer unsubscribe()
+
+		assert.True(t, service.SetServerState(1, service.ServerStateDraining))
+		event := testing_util.WaitForEvent(t, events, service.EventServerStateChanged)
+		assert.Equal(t, service.ServerStateEvent{State: service.ServerStateDraining}, event.Data)
+
+		for _, player := range []string{"p1", "p2"} {
+			_, server, ok := service.CreatePlayerIfPossible(game, player)
+			assert.True(t, ok)
+			assert.Equal(t, 2, server)
+		}
+
+		start, _ := service.ShouldStartMatch(1)
+		assert.False(t, start)
+	})
+
+	t.Run("draining servers aren't revived by the planner", func(t *testing.T) {
+		plan := service.PlanCapacity()
+		assert.NotContains(t, plan.Revive, 1)
+
+		state, _ := service.GetServerState(1)
+		assert.Equal(t, service.ServerStateDraining, state)
+	})
+
+	t.Run("server stops once its last match ends", func(t *testing.T) {
+		events, unsubscribe := service.SubscribeToEvents(1)
+		defer unsubscribe()
+
+		assert.True(t, service.SetMatchState(1, 1, service.MatchStateEnd))
+		event := testing_util.WaitForEvent(t, events, service.EventServerStateChanged)
+		assert.Equal(t, service.ServerStateEvent{State: service.ServerStateStopped}, event.Data)
+
+		_, ok := service.GetServerState(1)
+		assert.False(t, ok)
+	})
+
+	t.Run("stopping servers can't go back or start new matches", func(t *testing.T) {
+		assert.True(t, service.SetServerState(2, service.ServerStateStopping))
+		assert.False(t, service.SetServerState(2, service.ServerStateReady))
+		assert.False(t, service.AddMatch(2, service.MatchCreate{
+			ID:   2,
+			Game: game,
+		}, []string{"a"}))
+
+		// The match that's still running is left alone
+		_, ok := service.GetMatchFromServer(2, 1)
+		assert.True(t, ok)
+		assert.True(t, service.IsOnServerOrWaiting("p1"))
+	})
+
+	t.Run("evicting a server doesn't end matches in progress", func(t *testing.T) {
+		assert.True(t, service.SetMatchState(2, 1, service.MatchStateFull))
+		assert.True(t, service.EvictServer(2))
+
+		// The match and its players are kept until the server comes back or stops
+		_, ok := service.GetMatchFromServer(2, 1)
+		assert.True(t, ok)
+		assert.True(t, service.IsOnServerOrWaiting("p1"))
+		ttl, _ := service.ServerTTLLeft(2)
+		assert.Greater(t, ttl, service.ServerTTL)
+	})
+
+	t.Run("evicted server stops once the match in progress ends", func(t *testing.T) {
+		assert.True(t, service.SetMatchState(2, 1, service.MatchStateEnd))
+		_, ok := service.GetServerState(2)
+		assert.False(t, ok)
+		assert.Eventually(t, func() bool { return !service.IsOnServerOrWaiting("p1") }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("matches that didn't start are ended when evicting", func(t *testing.T) {
+		assert.True(t, service.CreateServer(4, "localhost", 3004))
+		for match, player := range map[int]string{1: "p3", 2: "p4"} {
+			assert.True(t, service.AddMatch(4, service.MatchCreate{
+				ID:   match,
+				Game: game,
+			}, []string{"a"}))
+			assert.True(t, service.SetMatchState(4, match, service.MatchStateAccepting))
+			_, server, ok := service.CreatePlayerIfPossible(game, player)
+			assert.True(t, ok)
+			assert.Equal(t, 4, server)
+			assert.True(t, service.SetMatchState(4, match, service.MatchStateFull))
+		}
+		assert.True(t, service.SetMatchState(4, 2, service.MatchStateAccepting))
+		assert.True(t, service.EvictServer(4))
+
+		_, ok := service.GetMatchFromServer(4, 2)
+		assert.False(t, ok)
+		assert.Eventually(t, func() bool { return !service.IsOnServerOrWaiting("p4") }, time.Second, 10*time.Millisecond)
+		assert.True(t, service.IsOnServerOrWaiting("p3"))
+
+		start, _ := service.ShouldStartMatch(4)
+		assert.False(t, start)
+	})
+
+	t.Run("evicted servers can come back by renewing", func(t *testing.T) {
+		service.RefreshServer(4)
+		ttl, _ := service.ServerTTLLeft(4)
+		assert.LessOrEqual(t, ttl, service.ServerTTL)
+
+		start, _ := service.ShouldStartMatch(4)
+		assert.True(t, start)
+	})
+
+	t.Run("servers evicted twice in a row are removed", func(t *testing.T) {
+		assert.True(t, service.EvictServer(4))
+		assert.True(t, service.EvictServer(4))
+
+		_, ok := service.GetServerState(4)
+		assert.False(t, ok)
+		assert.Eventually(t, func() bool { return !service.IsOnServerOrWaiting("p3") }, time.Second, 10*time.Millisecond)
+	})
+}
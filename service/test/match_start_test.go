@@ -0,0 +1,103 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchStart(t *testing.T) {
+	service.ResetAll()
+
+	const (
+		id   = 1
+		game = "battle"
+	)
+
+	assert.True(t, service.CreateServer(id, "localhost", 3000))
+	assert.True(t, service.AddMatch(id, service.MatchCreate{
+		ID:              1,
+		Game:            game,
+		MinPlayers:      2,
+		MaxPlayers:      3,
+		ForceStartAfter: 1,
+	}, []string{"a", "b", "c", "d"}))
+	service.SetMatchState(id, 1, service.MatchStateAccepting)
+
+	events, unsubscribe := service.SubscribeToEvents(id)
+	defer unsubscribe()
+
+	join := func(t *testing.T, account string) {
+		token, server, ok := service.CreatePlayerIfPossible(game, account)
+		assert.True(t, ok)
+		_, ok = service.ConfirmPlayerToken(server, account, token)
+		assert.True(t, ok)
+	}
+
+	t.Run("not ready with a single player", func(t *testing.T) {
+		join(t, "player1")
+
+		readiness, ok := service.GetMatchReadiness(id, 1)
+		assert.True(t, ok)
+		assert.False(t, readiness.Ready)
+		assert.Equal(t, 1, readiness.Confirmed)
+		assert.InDelta(t, 1.0/3, readiness.Fill, 0.001)
+	})
+
+	t.Run("reserved players don't count", func(t *testing.T) {
+		_, _, ok := service.CreatePlayerIfPossible(game, "player2")
+		assert.True(t, ok)
+
+		readiness, _ := service.GetMatchReadiness(id, 1)
+		assert.False(t, readiness.Ready)
+		assert.Equal(t, 2, readiness.Players)
+		service.DeletePlayer("player2", nil)
+	})
+
+	t.Run("ready at min players", func(t *testing.T) {
+		join(t, "player2")
+		event := testing_util.WaitForEvent(t, events, service.EventMatchReady)
+		assert.Equal(t, 1, event.Data.(service.MatchEvent).Match)
+
+		readiness, _ := service.GetMatchReadiness(id, 1)
+		assert.True(t, readiness.Ready)
+		assert.WithinDuration(t, readiness.ReadySince.Add(time.Second), readiness.ForceStartAt, 0)
+	})
+
+	t.Run("cancelled when a player leaves", func(t *testing.T) {
+		service.DeletePlayer("player2", nil)
+		testing_util.WaitForEvent(t, events, service.EventMatchReadyCancelled)
+
+		readiness, _ := service.GetMatchReadiness(id, 1)
+		assert.False(t, readiness.Ready)
+	})
+
+	t.Run("max players are respected", func(t *testing.T) {
+		join(t, "player2")
+		testing_util.WaitForEvent(t, events, service.EventMatchReady)
+		join(t, "player3")
+
+		_, _, ok := service.CreatePlayerIfPossible(game, "player4")
+		assert.False(t, ok)
+
+		readiness, _ := service.GetMatchReadiness(id, 1)
+		assert.Equal(t, 1.0, readiness.Fill)
+	})
+
+	t.Run("force start after the timeout", func(t *testing.T) {
+		timeout := time.After(2 * time.Second)
+		for {
+			select {
+			case event := <-events:
+				if event.Type == service.EventMatchForceStart {
+					return
+				}
+			case <-timeout:
+				t.Fatal("match wasn't force started")
+			}
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchStateMachine(t *testing.T) {
+	service.ResetAll()
+
+	const (
+		game    = "battle"
+		server  = 1
+		matchId = 1
+	)
+
+	assert.True(t, service.CreateServer(server, "localhost", 3000))
+	assert.True(t, service.AddMatch(server, service.MatchCreate{
+		ID:   matchId,
+		Game: game,
+	}, []string{"a", "b", "c"}))
+
+	t.Run("unknown states are rejected", func(t *testing.T) {
+		err := service.TransitionMatch(server, matchId, "acepting")
+		assert.ErrorIs(t, err, service.ErrUnknownMatchState)
+		assert.Contains(t, err.Error(), "acepting")
+
+		assert.ErrorIs(t, service.TransitionMatch(server, 67, service.MatchStateAccepting), service.ErrMatchNotFound)
+	})
+
+	t.Run("accepting matches can be joined right away", func(t *testing.T) {
+		assert.Nil(t, service.TransitionMatch(server, matchId, service.MatchStateAccepting))
+
+		_, id, ok := service.CreatePlayerIfPossible(game, "p1")
+		assert.True(t, ok)
+		assert.Equal(t, server, id)
+	})
+
+	t.Run("full matches leave the selection", func(t *testing.T) {
+		assert.Nil(t, service.TransitionMatch(server, matchId, service.MatchStateFull))
+
+		_, _, ok := service.CreatePlayerIfPossible(game, "p2")
+		assert.False(t, ok)
+	})
+
+	t.Run("illegal transitions are rejected", func(t *testing.T) {
+		err := service.TransitionMatch(server, matchId, service.MatchStateAvailable)
+		assert.ErrorIs(t, err, service.ErrIllegalMatchTransition)
+
+		match, ok := service.GetMatchFromServer(server, matchId)
+		assert.True(t, ok)
+		assert.Equal(t, service.MatchStateFull, match.State)
+	})
+
+	t.Run("ended matches remove their players right away", func(t *testing.T) {
+		assert.Nil(t, service.TransitionMatch(server, matchId, service.MatchStateEnd))
+		assert.Eventually(t, func() bool { return !service.IsOnServerOrWaiting("p1") }, time.Second, 10*time.Millisecond)
+
+		mr, ok := service.GetMatchRegistry(game)
+		assert.True(t, ok)
+		_, ok = mr.GetMatch(matchId)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,90 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	service.ResetAll()
+	t.Setenv("TOKEN_FILE_LOCATION", t.TempDir())
+
+	const game = "battle"
+
+	// Get the value of a metric with the given labels (-1 when it doesn't exist)
+	metric := func(t *testing.T, name string, labels map[string]string) float64 {
+		families, err := service.MetricsRegistry.Gather()
+		assert.Nil(t, err)
+
+		for _, family := range families {
+			if family.GetName() != name {
+				continue
+			}
+
+		metrics:
+			for _, m := range family.GetMetric() {
+				for _, label := range m.GetLabel() {
+					if labels[label.GetName()] != label.GetValue() {
+						continue metrics
+					}
+				}
+
+				switch {
+				case m.GetGauge() != nil:
+					return m.GetGauge().GetValue()
+				case m.GetCounter() != nil:
+					return m.GetCounter().GetValue()
+				case m.GetHistogram() != nil:
+					return float64(m.GetHistogram().GetSampleCount())
+				}
+			}
+		}
+		return -1
+	}
+
+	service.AddToken(service.Token{})
+	service.AddToken(service.Token{})
+	service.GetFreeToken()
+
+	assert.True(t, service.CreateServer(1, "localhost", 3000))
+	assert.True(t, service.AddMatch(1, service.MatchCreate{
+		ID:   1,
+		Game: game,
+	}, []string{"a", "b"}))
+	assert.True(t, service.SetMatchState(1, 1, service.MatchStateAccepting))
+
+	// Two players get a slot and one has to wait
+	for _, player := range []string{"p1", "p2", "p3"} {
+		_, ok := service.QueuePlayer(game, player)
+		assert.True(t, ok)
+	}
+	token, _ := service.GetQueueStatus("p1")
+	_, ok := service.ConfirmPlayerToken(1, "p1", token.Tokens["p1"])
+	assert.True(t, ok)
+
+	t.Run("state gauges", func(t *testing.T) {
+		assert.Equal(t, 1.0, metric(t, "hytale_matchmaking_servers", nil))
+		assert.Equal(t, 1.0, metric(t, "hytale_matchmaking_tokens", map[string]string{"state": "free"}))
+		assert.Equal(t, 1.0, metric(t, "hytale_matchmaking_tokens", map[string]string{"state": "used"}))
+		assert.Equal(t, 1.0, metric(t, "hytale_matchmaking_matches", map[string]string{"game": game, "state": service.MatchStateAccepting}))
+		assert.Equal(t, 1.0, metric(t, "hytale_matchmaking_players", map[string]string{"state": "queued"}))
+		assert.Equal(t, 1.0, metric(t, "hytale_matchmaking_players", map[string]string{"state": "reserved"}))
+		assert.Equal(t, 1.0, metric(t, "hytale_matchmaking_players", map[string]string{"state": "confirmed"}))
+		assert.Equal(t, 1.0, metric(t, "hytale_matchmaking_match_fill_ratio", map[string]string{"game": game}))
+	})
+
+	t.Run("queue wait is recorded for assigned players", func(t *testing.T) {
+		assert.GreaterOrEqual(t, metric(t, "hytale_matchmaking_queue_wait_seconds", map[string]string{"game": game}), 2.0)
+	})
+
+	t.Run("servers removed by hand aren't counted as evicted", func(t *testing.T) {
+		before := metric(t, "hytale_matchmaking_server_evictions_total", nil)
+		assert.True(t, service.CreateServer(2, "localhost", 3001))
+		assert.True(t, service.EvictServer(2))
+		service.ResetAll()
+		assert.Equal(t, before, metric(t, "hytale_matchmaking_server_evictions_total", nil))
+		assert.Equal(t, 0.0, metric(t, "hytale_matchmaking_servers", nil))
+	})
+}
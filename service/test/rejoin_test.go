@@ -0,0 +1,168 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRejoin(t *testing.T) {
+	service.ResetAll()
+
+	const (
+		game    = "battle"
+		server  = 1
+		matchId = 1
+	)
+
+	// Two matches, so a player that isn't rejoining would end up in the other one
+	assert.True(t, service.CreateServer(server, "localhost", 3000))
+	for id := 1; id <= 2; id++ {
+		assert.True(t, service.AddMatch(server, service.MatchCreate{
+			ID:              id,
+			Game:            game,
+			SpectatorTokens: []string{"s"},
+		}, []string{"a", "b", "c"}))
+	}
+	assert.True(t, service.SetMatchState(server, matchId, service.MatchStateAccepting))
+
+	// Queued like everyone else (so the queue doesn't get in the way of rejoining later)
+	status, ok := service.QueuePlayer(game, "player")
+	assert.True(t, ok)
+	assert.True(t, status.Assigned)
+	token := status.Tokens["player"]
+	_, ok = service.ConfirmPlayerToken(server, "player", token)
+	assert.True(t, ok)
+
+	// The match starts and the other one is the only one players can join now
+	assert.True(t, service.SetMatchState(server, matchId, service.MatchStateFull))
+	assert.True(t, service.SetMatchState(server, 2, service.MatchStateAccepting))
+
+	events, unsubscribe := service.SubscribeToEvents(server)
+	defer unsubscribe()
+
+	t.Run("players still on the server can't rejoin", func(t *testing.T) {
+		_, ok := service.QueuePlayer(game, "player")
+		assert.False(t, ok)
+
+		// They might still be connected, so they keep their slot as it is
+		lookup, ok := service.LookupPlayer("player")
+		assert.True(t, ok)
+		assert.True(t, lookup.Player.Confirmed)
+	})
+
+	t.Run("players that disconnected get their slot back", func(t *testing.T) {
+		assert.True(t, service.LeavePlayer(server, service.PlayerLeave{
+			Player: "player",
+			Reason: service.LeaveReasonDisconnected,
+		}))
+		assert.False(t, service.IsOnServerOrWaiting("player"))
+
+		status, ok := service.QueuePlayer(game, "player")
+		assert.True(t, ok)
+		assert.True(t, status.Rejoin)
+		assert.Equal(t, server, status.Server)
+		assert.Contains(t, []string{"a", "b", "c"}, status.Tokens["player"]) // From the tokens of the match
+
+		event := testing_util.WaitForEvent(t, events, service.EventPlayerReserved)
+		assert.Equal(t, service.PlayerEvent{
+			Player: "player",
+			Match:  matchId,
+			Token:  status.Tokens["player"],
+			Rejoin: true,
+		}, event.Data)
+
+		lookup, ok := service.LookupPlayer("player")
+		assert.True(t, ok)
+		assert.Equal(t, matchId, lookup.Player.Match)
+
+		match, ok := service.ConfirmPlayerToken(server, "player", status.Tokens["player"])
+		assert.True(t, ok)
+		assert.Equal(t, matchId, match)
+	})
+
+	t.Run("players with a slot somewhere else can't rejoin", func(t *testing.T) {
+		assert.True(t, service.LeavePlayer(server, service.PlayerLeave{
+			Player: "player",
+			Reason: service.LeaveReasonDisconnected,
+		}))
+		_, _, _, ok := service.CreateSpectatorIfPossible(service.SpectateTarget{Server: server, Match: 2}, "player")
+		assert.True(t, ok)
+
+		_, ok = service.QueuePlayer(game, "player")
+		assert.False(t, ok)
+
+		// Taking the other slot gave up the old one
+		service.DeletePlayer("player", nil)
+		status, ok := service.QueuePlayer(game, "player")
+		assert.True(t, ok)
+		assert.False(t, status.Rejoin)
+
+		match, ok := service.GetMatchFromServer(server, matchId)
+		assert.True(t, ok)
+		match.Mutex.RLock()
+		assert.NotContains(t, match.Players, "player")
+		match.Mutex.RUnlock()
+	})
+
+	t.Run("players that quit get a new match", func(t *testing.T) {
+		assert.True(t, service.LeavePlayer(server, service.PlayerLeave{
+			Player: "player",
+			Reason: service.LeaveReasonQuit,
+		}))
+
+		status, ok := service.QueuePlayer(game, "player")
+		assert.True(t, ok)
+		assert.False(t, status.Rejoin)
+
+		lookup, ok := service.LookupPlayer("player")
+		assert.True(t, ok)
+		assert.Equal(t, 2, lookup.Player.Match)
+		service.DeletePlayer("player", nil)
+	})
+
+	t.Run("players can't rejoin once the window is over", func(t *testing.T) {
+		assert.True(t, service.AddMatch(server, service.MatchCreate{
+			ID:           4,
+			Game:         game,
+			RejoinWindow: 1,
+		}, []string{"y"}))
+
+		status, ok := service.QueuePlayer(game, "late")
+		assert.True(t, ok)
+		_, ok = service.ConfirmPlayerToken(server, "late", status.Tokens["late"])
+		assert.True(t, ok)
+		assert.True(t, service.LeavePlayer(server, service.PlayerLeave{
+			Player: "late",
+			Reason: service.LeaveReasonDisconnected,
+		}))
+
+		time.Sleep(1100 * time.Millisecond)
+		status, ok = service.QueuePlayer(game, "late")
+		assert.True(t, ok)
+		assert.False(t, status.Rejoin)
+		service.DeletePlayer("late", nil)
+	})
+
+	t.Run("nobody rejoins when the game doesn't allow it", func(t *testing.T) {
+		assert.True(t, service.AddMatch(server, service.MatchCreate{
+			ID:           3,
+			Game:         game,
+			RejoinWindow: -1,
+		}, []string{"x"}))
+		mr, ok := service.GetMatchRegistry(game)
+		assert.True(t, ok)
+		assert.Less(t, mr.RejoinWindow(), time.Duration(0))
+
+		status, ok := service.QueuePlayer(game, "other")
+		assert.True(t, ok)
+		_, ok = service.ConfirmPlayerToken(server, "other", status.Tokens["other"])
+		assert.True(t, ok)
+
+		_, ok = service.QueuePlayer(game, "other")
+		assert.False(t, ok)
+	})
+}
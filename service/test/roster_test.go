@@ -0,0 +1,82 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRosterReconciliation(t *testing.T) {
+	service.ResetAll()
+
+	const (
+		game    = "battle"
+		server  = 1
+		matchId = 1
+	)
+
+	assert.True(t, service.CreateServer(server, "localhost", 3000))
+	assert.True(t, service.AddMatch(server, service.MatchCreate{
+		ID:   matchId,
+		Game: game,
+	}, []string{"a", "b", "c", "d"}))
+	assert.True(t, service.SetMatchState(server, matchId, service.MatchStateAccepting))
+
+	// One player that joined, one that only has a reservation
+	token, _, ok := service.CreatePlayerIfPossible(game, "joined")
+	assert.True(t, ok)
+	_, ok = service.ConfirmPlayerToken(server, "joined", token)
+	assert.True(t, ok)
+	_, _, ok = service.CreatePlayerIfPossible(game, "reserved")
+	assert.True(t, ok)
+
+	match, ok := service.GetMatchFromServer(server, matchId)
+	assert.True(t, ok)
+	state := func() ([]string, int) {
+		match.Mutex.RLock()
+		defer match.Mutex.RUnlock()
+		return append([]string{}, match.Players...), len(match.TokenStore)
+	}
+
+	t.Run("nothing changes when the roster agrees", func(t *testing.T) {
+		corrections := service.ReconcileRoster(server, []service.MatchRoster{{Match: matchId, Players: []string{"joined"}}})
+		assert.Empty(t, corrections.Added)
+		assert.Empty(t, corrections.Removed)
+		assert.True(t, service.IsOnServerOrWaiting("reserved"))
+	})
+
+	t.Run("missing players are added", func(t *testing.T) {
+		corrections := service.ReconcileRoster(server, []service.MatchRoster{{Match: matchId, Players: []string{"joined", "reserved", "unknown"}}})
+		assert.ElementsMatch(t, []string{"reserved", "unknown"}, corrections.Added)
+		assert.Empty(t, corrections.Removed)
+
+		players, tokens := state()
+		assert.ElementsMatch(t, []string{"joined", "reserved", "unknown"}, players)
+		assert.Equal(t, 1, tokens)
+
+		// Both are confirmed now, so the reservation timeout doesn't apply anymore
+		status, ok := service.LookupPlayer("reserved")
+		assert.True(t, ok)
+		assert.True(t, status.Player.Confirmed)
+		assert.True(t, service.IsOnServerOrWaiting("unknown"))
+	})
+
+	t.Run("ghosts are removed", func(t *testing.T) {
+		corrections := service.ReconcileRoster(server, []service.MatchRoster{{Match: matchId, Players: []string{"unknown"}}})
+		assert.Empty(t, corrections.Added)
+		assert.ElementsMatch(t, []string{"joined", "reserved"}, corrections.Removed)
+
+		players, tokens := state()
+		assert.Equal(t, []string{"unknown"}, players)
+		assert.Equal(t, 3, tokens)
+		assert.Eventually(t, func() bool { return !service.IsOnServerOrWaiting("joined") }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("unknown matches are skipped", func(t *testing.T) {
+		corrections := service.ReconcileRoster(server, []service.MatchRoster{{Match: 67, Players: []string{"someone"}}})
+		assert.Empty(t, corrections.Added)
+		assert.False(t, service.IsOnServerOrWaiting("someone"))
+	})
+}
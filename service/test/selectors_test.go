@@ -0,0 +1,90 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchSelectors(t *testing.T) {
+
+	// Create a few servers with one match each (server 1 has the most players, server 3 the least)
+	setup := func(t *testing.T, game string, selector string) {
+		service.ResetAll()
+
+		for id := 1; id <= 3; id++ {
+			assert.True(t, service.CreateServer(id, "localhost", 3000+id))
+			assert.True(t, service.AddMatch(id, service.MatchCreate{
+				ID:       1,
+				Game:     game,
+				Selector: selector,
+			}, []string{"a", "b", "c", "d", "e", "f"}))
+			assert.True(t, service.SetMatchState(id, 1, service.MatchStateAccepting))
+		}
+
+		mr, ok := service.GetMatchRegistry(game)
+		assert.True(t, ok)
+		assert.Equal(t, selector, mr.Selector())
+
+		for _, seat := range []struct {
+			server  int
+			players int
+		}{{1, 3}, {2, 2}, {3, 1}} {
+			match, ok := service.GetMatchFromServer(seat.server, 1)
+			assert.True(t, ok)
+			for i := range seat.players {
+				_, ok := match.AddPlayerIfPossible(string(rune('a'+seat.server)) + string(rune('0'+i)))
+				assert.True(t, ok)
+			}
+		}
+	}
+
+	t.Run("fill fullest", func(t *testing.T) {
+		setup(t, "fill", service.SelectorFillFullest)
+		_, server, ok := service.CreatePlayerIfPossible("fill", "player")
+		assert.True(t, ok)
+		assert.Equal(t, 1, server)
+	})
+
+	t.Run("least loaded", func(t *testing.T) {
+		setup(t, "least", service.SelectorLeastLoaded)
+		_, server, ok := service.CreatePlayerIfPossible("least", "player")
+		assert.True(t, ok)
+		assert.Equal(t, 3, server)
+	})
+
+	t.Run("round robin", func(t *testing.T) {
+		setup(t, "robin", service.SelectorRoundRobin)
+		servers := []int{}
+		for _, player := range []string{"p1", "p2", "p3", "p4"} {
+			_, server, ok := service.CreatePlayerIfPossible("robin", player)
+			assert.True(t, ok)
+			servers = append(servers, server)
+		}
+		assert.Equal(t, []int{1, 2, 3, 1}, servers)
+	})
+
+	t.Run("random", func(t *testing.T) {
+		setup(t, "random", service.SelectorRandom)
+		_, server, ok := service.CreatePlayerIfPossible("random", "player")
+		assert.True(t, ok)
+		assert.Contains(t, []int{1, 2, 3}, server)
+	})
+
+	t.Run("custom selectors can be registered", func(t *testing.T) {
+		service.RegisterMatchSelector("nobody", func() service.MatchSelector {
+			return &nobodySelector{}
+		})
+		setup(t, "custom", "nobody")
+		_, _, ok := service.CreatePlayerIfPossible("custom", "player")
+		assert.False(t, ok)
+	})
+}
+
+// Selector that never picks a match
+type nobodySelector struct{}
+
+func (*nobodySelector) Select(request service.SelectionRequest, candidates []service.MatchCandidate) *service.Match {
+	return nil
+}
@@ -0,0 +1,108 @@
+package service_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkillMatchmaking(t *testing.T) {
+	service.ResetAll()
+	t.Setenv("TOKEN_FILE_LOCATION", t.TempDir())
+
+	const game = "battle"
+
+	// Make a veteran by letting them win a lot of matches (on another server so they're gone afterwards)
+	assert.True(t, service.CreateServer(2, "localhost", 3001))
+	for id := 1; id <= 20; id++ {
+		assert.True(t, service.AddMatch(2, service.MatchCreate{
+			ID:       id,
+			Game:     game,
+			Selector: service.SelectorSkill,
+		}, []string{"a", "b"}))
+		assert.True(t, service.SetMatchState(2, id, service.MatchStateAccepting))
+		tokens, _, ok := service.CreatePartyIfPossible(game, []string{"veteran", "victim"})
+		assert.True(t, ok)
+		for i, account := range []string{"veteran", "victim"} {
+			_, ok := service.ConfirmToken(2, account, tokens[i])
+			assert.True(t, ok)
+		}
+
+		_, ok = service.ReportMatchResult(2, id, [][]string{{"veteran"}, {"victim"}})
+		assert.True(t, ok)
+		assert.True(t, service.SetMatchState(2, id, service.MatchStateEnd))
+		assert.Eventually(t, func() bool {
+			return !service.IsOnServerOrWaiting("veteran") && !service.IsOnServerOrWaiting("victim")
+		}, time.Second, 10*time.Millisecond)
+	}
+	assert.Greater(t, service.GetRating(game, "veteran"), service.DefaultRating+150)
+	assert.True(t, service.EvictServer(2))
+
+	// Two matches, one for veterans and one for new players
+	assert.True(t, service.CreateServer(1, "localhost", 3000))
+	for id := 1; id <= 3; id++ {
+		assert.True(t, service.AddMatch(1, service.MatchCreate{
+			ID:       id,
+			Game:     game,
+			Selector: service.SelectorSkill,
+		}, []string{"a", "b", "c", "d"}))
+	}
+	assert.True(t, service.SetMatchState(1, 1, service.MatchStateAccepting))
+	assert.True(t, service.SetMatchState(1, 2, service.MatchStateAccepting))
+
+	_, server, ok := service.CreatePlayerIfPossible(game, "veteran")
+	assert.True(t, ok)
+	assert.Equal(t, 1, server)
+
+	t.Run("new players avoid the veteran's match", func(t *testing.T) {
+		_, ok := service.QueuePlayer(game, "newbie")
+		assert.True(t, ok)
+
+		match, ok := service.GetMatchFromServer(1, 1)
+		assert.True(t, ok)
+		other, ok := service.GetMatchFromServer(1, 2)
+		assert.True(t, ok)
+
+		newbieMatch := match
+		if len(match.Players) == 1 {
+			newbieMatch = other
+		}
+		assert.Equal(t, []string{"newbie"}, newbieMatch.Players)
+	})
+
+	t.Run("players close to the rating join the same match", func(t *testing.T) {
+		_, ok := service.QueuePlayer(game, "newbie2")
+		assert.True(t, ok)
+
+		match, ok := service.GetMatchFromServer(1, 2)
+		assert.True(t, ok)
+		if !slices.Contains(match.Players, "newbie") {
+			match, ok = service.GetMatchFromServer(1, 1)
+			assert.True(t, ok)
+		}
+		assert.ElementsMatch(t, []string{"newbie", "newbie2"}, match.Players)
+	})
+
+	t.Run("fill fullest is still the default", func(t *testing.T) {
+		mr, ok := service.GetMatchRegistry("other")
+		assert.False(t, ok)
+		assert.True(t, service.AddMatch(1, service.MatchCreate{
+			ID:   4,
+			Game: "other",
+		}, []string{"a"}))
+		mr, ok = service.GetMatchRegistry("other")
+		assert.True(t, ok)
+		assert.Equal(t, service.SelectorFillFullest, mr.Selector())
+	})
+
+	t.Run("unknown selectors are refused", func(t *testing.T) {
+		assert.False(t, service.AddMatch(1, service.MatchCreate{
+			ID:       5,
+			Game:     game,
+			Selector: "chaos",
+		}, []string{"a"}))
+	})
+}
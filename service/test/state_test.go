@@ -0,0 +1,89 @@
+package service_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateRestore(t *testing.T) {
+	service.ResetAll()
+
+	const (
+		serverId = 1
+		server   = "localhost"
+		port     = 3000
+		game     = "battle"
+		matchId  = 1
+	)
+
+	// Create a match with one player that joined and one that only has a reservation
+	assert.True(t, service.CreateServer(serverId, server, port))
+	secret, ok := service.IssueServerSecret(serverId)
+	assert.True(t, ok)
+	assert.True(t, service.AddMatch(serverId, service.MatchCreate{
+		ID:   matchId,
+		Game: game,
+	}, []string{"a", "b", "c"}))
+	assert.True(t, service.SetMatchState(serverId, matchId, service.MatchStateAccepting))
+
+	token, _, ok := service.CreatePlayerIfPossible(game, "joined")
+	assert.True(t, ok)
+	_, ok = service.ConfirmPlayerToken(serverId, "joined", token)
+	assert.True(t, ok)
+	_, _, ok = service.CreatePlayerIfPossible(game, "reserved")
+	assert.True(t, ok)
+
+	store := service.NewFileStateStore(filepath.Join(t.TempDir(), service.StateFileName))
+
+	t.Run("nothing is loaded before the first save", func(t *testing.T) {
+		_, ok, err := store.Load()
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("state survives a restart", func(t *testing.T) {
+		assert.Nil(t, store.Save(service.TakeSnapshot()))
+		service.ResetAll()
+
+		snapshot, ok, err := store.Load()
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		service.RestoreSnapshot(snapshot)
+
+		ip, p, ok := service.GetServerDetails(serverId)
+		assert.True(t, ok)
+		assert.Equal(t, server, ip)
+		assert.Equal(t, port, p)
+		assert.Equal(t, secret, service.TakeSnapshot().Servers[0].Secret) // The server has to keep using its secret
+
+		// The match should be back in the registry with the reservation released
+		reg, ok := service.GetMatchRegistry(game)
+		assert.True(t, ok)
+		match, ok := reg.GetMatch(matchId)
+		assert.True(t, ok)
+		assert.Equal(t, service.MatchStateAccepting, match.State)
+		assert.Equal(t, []string{"joined"}, match.Players)
+		assert.ElementsMatch(t, []string{"b", "c"}, match.TokenStore)
+
+		assert.True(t, service.IsOnServerOrWaiting("joined"))
+		assert.False(t, service.IsOnServerOrWaiting("reserved"))
+	})
+
+	t.Run("restored servers only have the grace window", func(t *testing.T) {
+		ttl, ok := service.ServerTTLLeft(serverId)
+		assert.True(t, ok)
+		assert.Greater(t, ttl, service.ServerTTL)
+		assert.LessOrEqual(t, ttl, service.RestoreGraceWindow)
+
+		// Renewing should put the server back on the regular TTL
+		service.RefreshServer(serverId)
+		ttl, ok = service.ServerTTLLeft(serverId)
+		assert.True(t, ok)
+		assert.LessOrEqual(t, ttl, service.ServerTTL)
+		assert.Greater(t, ttl, service.ServerTTL-5*time.Second)
+	})
+}
@@ -0,0 +1,105 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoinTickets(t *testing.T) {
+	service.ResetAll()
+	t.Setenv("TOKEN_FILE_LOCATION", t.TempDir())
+
+	const (
+		id   = 1
+		game = "battle"
+	)
+
+	t.Run("key is persisted", func(t *testing.T) {
+		service.LoadTicketKey()
+		key := service.TicketPublicKey()
+		service.LoadTicketKey()
+		assert.Equal(t, key, service.TicketPublicKey())
+	})
+
+	assert.True(t, service.CreateServer(id, "localhost", 3000))
+	assert.True(t, service.AddMatch(id, service.MatchCreate{
+		ID:         1,
+		Game:       game,
+		MaxPlayers: 2,
+	}, nil))
+	service.SetMatchState(id, 1, service.MatchStateAccepting)
+
+	token, _, ok := service.CreatePlayerIfPossible(game, "player")
+	assert.True(t, ok)
+
+	signed, ok := service.IssueJoinTicket("player")
+	assert.True(t, ok)
+	audience, ok := service.GetTicketAudience(id)
+	assert.True(t, ok)
+
+	t.Run("ticket binds the slot", func(t *testing.T) {
+		ticket, err := service.VerifyJoinTicket(signed, service.TicketPublicKey(), audience)
+		assert.Nil(t, err)
+		assert.Equal(t, "player", ticket.Account)
+		assert.Equal(t, 1, ticket.Match)
+		assert.Equal(t, token, ticket.Token)
+		assert.False(t, ticket.Spectator)
+	})
+
+	t.Run("ticket can't be used on other servers", func(t *testing.T) {
+		assert.True(t, service.CreateServer(id+1, "localhost", 3001))
+		other, ok := service.GetTicketAudience(id + 1)
+		assert.True(t, ok)
+
+		_, err := service.VerifyJoinTicket(signed, service.TicketPublicKey(), other)
+		assert.ErrorIs(t, err, service.ErrWrongServer)
+	})
+
+	t.Run("tampered tickets are rejected", func(t *testing.T) {
+		parts := strings.Split(signed, ".")
+		other, err := service.SignJoinTicket(service.JoinTicket{
+			Issuer:    service.TicketIssuer,
+			Account:   "someone-else",
+			Audience:  audience,
+			ExpiresAt: time.Now().Add(time.Minute).Unix(),
+		})
+		assert.Nil(t, err)
+		forged := parts[0] + "." + strings.Split(other, ".")[1] + "." + parts[2]
+
+		_, err = service.VerifyJoinTicket(forged, service.TicketPublicKey(), audience)
+		assert.ErrorIs(t, err, service.ErrInvalidTicket)
+	})
+
+	t.Run("expired tickets are rejected", func(t *testing.T) {
+		expired, err := service.SignJoinTicket(service.JoinTicket{
+			Issuer:    service.TicketIssuer,
+			Account:   "player",
+			Audience:  audience,
+			ExpiresAt: time.Now().Add(-time.Second).Unix(),
+		})
+		assert.Nil(t, err)
+
+		_, err = service.VerifyJoinTicket(expired, service.TicketPublicKey(), audience)
+		assert.ErrorIs(t, err, service.ErrTicketExpired)
+	})
+
+	t.Run("tickets stop working when the id is registered again", func(t *testing.T) {
+		assert.True(t, service.EvictServer(id))
+		assert.True(t, service.CreateServer(id, "localhost", 3000))
+		reused, ok := service.GetTicketAudience(id)
+		assert.True(t, ok)
+		assert.NotEqual(t, audience, reused)
+
+		_, err := service.VerifyJoinTicket(signed, service.TicketPublicKey(), reused)
+		assert.ErrorIs(t, err, service.ErrWrongServer)
+	})
+
+	t.Run("no ticket without a slot", func(t *testing.T) {
+		_, ok := service.IssueJoinTicket("nobody")
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,98 @@
+package service_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	testing_util "github.com/Liphium/hytale-matchmaking/util/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenRefresh(t *testing.T) {
+	service.ResetAll()
+	t.Setenv("TOKEN_FILE_LOCATION", t.TempDir())
+
+	// Local stand-in for the Hytale token endpoint
+	var requests atomic.Int32
+	oauth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.FormValue("grant_type") != "refresh_token" || r.FormValue("refresh_token") != "refresh-old" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(service.TokenResponse{Error: "invalid_grant"})
+			return
+		}
+
+		json.NewEncoder(w).Encode(service.TokenResponse{
+			AccessToken:  "access-new",
+			RefreshToken: "refresh-new",
+			ExpiresIn:    3600,
+		})
+	}))
+	defer oauth.Close()
+	t.Setenv("HYTALE_TOKEN_URL", oauth.URL)
+
+	// One token that's about to expire (and used by a server) and one that's still fresh
+	expiring := service.AddToken(service.Token{
+		AccessToken:  "access-old",
+		RefreshToken: "refresh-old",
+		ExpiresAt:    time.Now().Add(time.Minute),
+	})
+	info, ok := service.GetFreeToken()
+	assert.True(t, ok)
+	assert.Equal(t, expiring, info.Id)
+	fresh := service.AddToken(service.Token{
+		AccessToken:  "access-fresh",
+		RefreshToken: "refresh-fresh",
+		ExpiresAt:    time.Now().Add(time.Hour),
+	})
+
+	events, unsubscribe := service.SubscribeToEvents(expiring)
+	defer unsubscribe()
+
+	t.Run("only expiring tokens are refreshed", func(t *testing.T) {
+		assert.Equal(t, 1, service.RefreshExpiringTokens())
+		assert.Equal(t, int32(1), requests.Load())
+
+		token, ok := service.GetToken(expiring)
+		assert.True(t, ok)
+		assert.Equal(t, "access-new", token.AccessToken)
+		assert.Equal(t, "refresh-new", token.RefreshToken)
+		assert.Greater(t, time.Until(token.ExpiresAt), 50*time.Minute)
+
+		token, ok = service.GetToken(fresh)
+		assert.True(t, ok)
+		assert.Equal(t, "access-fresh", token.AccessToken)
+	})
+
+	t.Run("server using the token is told about the new one", func(t *testing.T) {
+		event := testing_util.WaitForEvent(t, events, service.EventAccessTokenRotated)
+		assert.Nil(t, event.Data) // The token itself isn't part of the event
+	})
+
+	t.Run("rejected refresh keeps the old token", func(t *testing.T) {
+		assert.NotNil(t, service.RefreshToken(fresh))
+
+		token, ok := service.GetToken(fresh)
+		assert.True(t, ok)
+		assert.Equal(t, "access-fresh", token.AccessToken)
+	})
+
+	t.Run("failed refreshes aren't tried again right away", func(t *testing.T) {
+		service.AddToken(service.Token{
+			AccessToken:  "access-broken",
+			RefreshToken: "refresh-broken", // No expiry, so it's refreshed right away
+		})
+
+		before := requests.Load()
+		assert.Equal(t, 0, service.RefreshExpiringTokens())
+		assert.Equal(t, before+1, requests.Load())
+
+		assert.Equal(t, 0, service.RefreshExpiringTokens())
+		assert.Equal(t, before+1, requests.Load())
+	})
+}
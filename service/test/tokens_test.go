@@ -0,0 +1,60 @@
+package service_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Liphium/hytale-matchmaking/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenPersistence(t *testing.T) {
+	service.ResetAll()
+	dir := t.TempDir()
+	t.Setenv("TOKEN_FILE_LOCATION", dir)
+
+	// A tokens file from before the ids were saved
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, service.TokenFileName), []byte(`[
+		{"access_token": "first", "uuid": "uuid-first"},
+		{"access_token": "second", "uuid": "uuid-second"}
+	]`), 0644))
+	service.LoadTokens()
+
+	t.Run("tokens without an id are numbered by position", func(t *testing.T) {
+		token, ok := service.GetToken(0)
+		assert.True(t, ok)
+		assert.Equal(t, "first", token.AccessToken)
+
+		token, ok = service.GetToken(1)
+		assert.True(t, ok)
+		assert.Equal(t, "second", token.AccessToken)
+	})
+
+	t.Run("ids survive a restart", func(t *testing.T) {
+		third := service.AddToken(service.Token{AccessToken: "third", UUID: "uuid-third"})
+		service.ResetAll()
+		service.LoadTokens()
+
+		for id, access := range map[int]string{0: "first", 1: "second", third: "third"} {
+			token, ok := service.GetToken(id)
+			assert.True(t, ok)
+			assert.Equal(t, access, token.AccessToken)
+		}
+		assert.Greater(t, service.AddToken(service.Token{AccessToken: "fourth"}), third)
+	})
+
+	t.Run("servers are only restored when their token is still the same", func(t *testing.T) {
+		service.RestoreSnapshot(service.StateSnapshot{
+			Servers: []service.ServerSnapshot{
+				{ID: 0, IP: "localhost", Port: 3000, TokenUUID: "uuid-first"},
+				{ID: 1, IP: "localhost", Port: 3001, TokenUUID: "uuid-somebody-else"},
+			},
+		})
+
+		_, _, ok := service.GetServerDetails(0)
+		assert.True(t, ok)
+		_, _, ok = service.GetServerDetails(1)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/util"
+)
+
+// Join tickets are JWTs signed with Ed25519 (EdDSA), servers can verify them with the key from /api/servers/jwks
+const (
+	TicketKeyFileName = "ticket_key.pem"
+	TicketIssuer      = "hytale-matchmaking"
+	TicketLifetime    = PlayerTokenTimeout // Nobody can join with the ticket after the reservation is gone anyway
+	TicketAlgorithm   = "EdDSA"
+	TicketNonceLength = 16 // Length of the random part of the audience of a server
+)
+
+var (
+	ErrInvalidTicket = errors.New("invalid ticket")
+	ErrTicketExpired = errors.New("ticket expired")
+	ErrWrongServer   = errors.New("ticket is for a different server")
+)
+
+// Everything in a join ticket (the standard claims are used where possible)
+type JoinTicket struct {
+	Issuer    string `json:"iss"`
+	Account   string `json:"sub"`
+	Audience  string `json:"aud"` // Audience of the server the ticket is for (its id and a nonce, so tickets stop working when the id is reused)
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	Token     string `json:"jti"` // The token of the player (for confirming the join)
+
+	Match     int  `json:"match"`
+	Spectator bool `json:"spectator,omitempty"`
+}
+
+type ticketHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// Public key in the JSON Web Key format (RFC 8037)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var ticketKey ed25519.PrivateKey
+var ticketKeyMutex = &sync.RWMutex{}
+
+// Load the key for signing tickets from the data directory (a new one is created in case there isn't one yet)
+func LoadTicketKey() {
+	file := path.Join(os.Getenv("TOKEN_FILE_LOCATION"), TicketKeyFileName)
+	content, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			log.Fatalln("Couldn't generate ticket key:", err)
+		}
+		bytes, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			log.Fatalln("Couldn't write ticket key (marshal):", err)
+		}
+		if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: bytes}), 0600); err != nil {
+			log.Fatalln("Couldn't write ticket key:", err)
+		}
+		setTicketKey(key)
+		return
+	}
+	if err != nil {
+		log.Fatalln("Couldn't read ticket key:", err)
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		log.Fatalln("Couldn't parse ticket key: no PEM block found")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		log.Fatalln("Couldn't parse ticket key:", err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		log.Fatalln("Couldn't parse ticket key: not an Ed25519 key")
+	}
+	setTicketKey(key)
+}
+
+func setTicketKey(key ed25519.PrivateKey) {
+	ticketKeyMutex.Lock()
+	defer ticketKeyMutex.Unlock()
+	ticketKey = key
+}
+
+// Helper function for getting the signing key (a temporary one is created in case none was loaded, e.g. in tests)
+func getTicketKey() ed25519.PrivateKey {
+	ticketKeyMutex.RLock()
+	key := ticketKey
+	ticketKeyMutex.RUnlock()
+	if key != nil {
+		return key
+	}
+
+	ticketKeyMutex.Lock()
+	defer ticketKeyMutex.Unlock()
+	if ticketKey == nil {
+		_, ticketKey, _ = ed25519.GenerateKey(rand.Reader)
+	}
+	return ticketKey
+}
+
+// Get the public key servers verify tickets with
+func TicketPublicKey() ed25519.PublicKey {
+	return getTicketKey().Public().(ed25519.PublicKey)
+}
+
+// Get the id of the current key (the first bytes of its hash)
+func ticketKeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return base64.RawURLEncoding.EncodeToString(sum[:8])
+}
+
+// Get the key set servers fetch for verifying tickets
+func GetJWKS() JWKS {
+	key := TicketPublicKey()
+	return JWKS{
+		Keys: []JWK{{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(key),
+			KeyID:     ticketKeyID(key),
+			Use:       "sig",
+			Algorithm: TicketAlgorithm,
+		}},
+	}
+}
+
+// Create the audience for a newly registered server
+func newTicketAudience(id int) string {
+	return fmt.Sprintf("%d.%s", id, util.GenerateToken(TicketNonceLength))
+}
+
+// Get the audience a server has to expect in its join tickets (it's told about it when registering)
+func GetTicketAudience(id int) (string, bool) {
+	server, ok := serverCache.Get(id)
+	if !ok {
+		return "", false
+	}
+
+	server.Mutex.RLock()
+	defer server.Mutex.RUnlock()
+	return server.Audience, true
+}
+
+// Create a ticket for the slot a player currently has (false when they don't have one)
+func IssueJoinTicket(account string) (string, bool) {
+	player, ok := getPlayer(account)
+	if !ok {
+		return "", false
+	}
+
+	player.Mutex.RLock()
+	now := time.Now()
+	server := player.Server
+	ticket := JoinTicket{
+		Issuer:    TicketIssuer,
+		Account:   player.Account,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(TicketLifetime).Unix(),
+		Token:     player.Token,
+		Match:     player.Match,
+		Spectator: player.Spectator,
+	}
+	player.Mutex.RUnlock()
+
+	if ticket.Audience, ok = GetTicketAudience(server); !ok {
+		return "", false
+	}
+
+	signed, err := SignJoinTicket(ticket)
+	if err != nil {
+		log.Println("Couldn't sign join ticket:", err)
+		return "", false
+	}
+	return signed, true
+}
+
+// Turn a ticket into a signed JWT
+func SignJoinTicket(ticket JoinTicket) (string, error) {
+	key := getTicketKey()
+	header, err := json.Marshal(ticketHeader{
+		Algorithm: TicketAlgorithm,
+		Type:      "JWT",
+		KeyID:     ticketKeyID(key.Public().(ed25519.PublicKey)),
+	})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(ticket)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature := ed25519.Sign(key, []byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Check a ticket the same way game servers do (the audience is the one of the server the player is joining)
+func VerifyJoinTicket(signed string, key ed25519.PublicKey, audience string) (JoinTicket, error) {
+	parts := strings.Split(signed, ".")
+	if len(parts) != 3 {
+		return JoinTicket{}, ErrInvalidTicket
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !ed25519.Verify(key, []byte(parts[0]+"."+parts[1]), signature) {
+		return JoinTicket{}, ErrInvalidTicket
+	}
+
+	var header ticketHeader
+	if err := decodeTicketPart(parts[0], &header); err != nil || header.Algorithm != TicketAlgorithm {
+		return JoinTicket{}, ErrInvalidTicket
+	}
+	var ticket JoinTicket
+	if err := decodeTicketPart(parts[1], &ticket); err != nil || ticket.Issuer != TicketIssuer {
+		return JoinTicket{}, ErrInvalidTicket
+	}
+
+	if time.Now().Unix() >= ticket.ExpiresAt {
+		return JoinTicket{}, ErrTicketExpired
+	}
+	if ticket.Audience != audience {
+		return JoinTicket{}, ErrWrongServer
+	}
+	return ticket, nil
+}
+
+// Helper function for decoding the header or payload of a ticket
+func decodeTicketPart(part string, v any) error {
+	bytes, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, v)
+}
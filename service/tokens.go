@@ -5,16 +5,25 @@ import (
 	"log"
 	"os"
 	"path"
+	"slices"
 	"sync"
+	"time"
 )
 
 const TokenFileName = "tokens.json"
 
 type Token struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	Account      string `json:"account"`
-	UUID         string `json:"uuid"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"` // When the access token expires (zero when unknown, refreshed right away)
+	Account      string    `json:"account"`
+	UUID         string    `json:"uuid"`
+}
+
+// How a token is saved in the tokens file (the id is kept since servers restored from a snapshot are found by it)
+type storedToken struct {
+	ID *int `json:"id,omitempty"` // Not set in files from before ids were saved (the position in the file is used then)
+	Token
 }
 
 type TokenInfo struct {
@@ -22,6 +31,11 @@ type TokenInfo struct {
 	Id    int
 	Used  bool
 	Token Token
+
+	Session *GameSession // Cached game session created with the token (not persisted)
+
+	failedRefreshes int       // Refreshes that failed in a row (see oauth.go)
+	retryRefreshAt  time.Time // The token isn't refreshed again before this after a failed refresh
 }
 
 var tokensMap = &sync.Map{}
@@ -33,7 +47,7 @@ func LoadTokens() {
 	tokensFile := path.Join(os.Getenv("TOKEN_FILE_LOCATION"), TokenFileName)
 	content, err := os.ReadFile(tokensFile)
 	if err != nil {
-		bytes, err := json.Marshal([]Token{})
+		bytes, err := json.Marshal([]storedToken{})
 		if err != nil {
 			log.Fatal("Couldn't write empty tokens file (marshal):", err)
 		}
@@ -44,21 +58,28 @@ func LoadTokens() {
 		content = bytes
 	}
 
-	var tokens []Token
+	var tokens []storedToken
 	if err := json.Unmarshal(content, &tokens); err != nil {
 		log.Fatal("Couldn't parse tokens file:", err)
 	}
 
 	// Fill the map with all of the tokens in the file
+	tokenCounterMutex.Lock()
+	defer tokenCounterMutex.Unlock()
 	for i, token := range tokens {
-		tokensMap.Store(i, &TokenInfo{
-			Id:    i,
+		id := i
+		if token.ID != nil {
+			id = *token.ID
+		}
+
+		tokensMap.Store(id, &TokenInfo{
+			Id:    id,
 			Used:  false,
 			Mutex: &sync.Mutex{},
-			Token: token,
+			Token: token.Token,
 		})
+		tokenCounter = max(tokenCounter, id+1)
 	}
-	tokenCounter = len(tokens) + 1
 }
 
 func GetFreeToken() (*TokenInfo, bool) {
@@ -83,7 +104,8 @@ func GetFreeToken() (*TokenInfo, bool) {
 	return foundServer, foundServer != nil
 }
 
-func ReplaceAccessToken(id int, accessToken string) {
+// Replace the access token of a token with one the server got itself (the refresh token stays with the matchmaker)
+func ReplaceAccessToken(id int, accessToken string, expiresAt time.Time) {
 	obj, ok := tokensMap.Load(id)
 	if !ok {
 		return
@@ -91,21 +113,26 @@ func ReplaceAccessToken(id int, accessToken string) {
 	info := obj.(*TokenInfo)
 
 	info.Mutex.Lock()
-	token := info.Token
-	token.AccessToken = accessToken
-	info.Token = token
-	tokensMap.Store(id, info)
+	info.Token.AccessToken = accessToken
+	info.Token.ExpiresAt = expiresAt
 	info.Mutex.Unlock()
 
+	tokenCounterMutex.Lock()
 	saveToTokens()
+	tokenCounterMutex.Unlock()
+
+	// Tell the server using the token about the change
+	publishEvent(id, Event{Type: EventAccessTokenRotated})
 }
 
-func AddToken(token Token) {
+// Add a new token to the pool (returns its id)
+func AddToken(token Token) int {
 	tokenCounterMutex.Lock()
 	defer tokenCounterMutex.Unlock()
 
-	tokensMap.Store(tokenCounter, &TokenInfo{
-		Id:    tokenCounter,
+	id := tokenCounter
+	tokensMap.Store(id, &TokenInfo{
+		Id:    id,
 		Used:  false,
 		Mutex: &sync.Mutex{},
 		Token: token,
@@ -113,6 +140,20 @@ func AddToken(token Token) {
 	tokenCounter++
 
 	saveToTokens()
+	return id
+}
+
+// Get a copy of a token from the pool
+func GetToken(id int) (Token, bool) {
+	obj, ok := tokensMap.Load(id)
+	if !ok {
+		return Token{}, false
+	}
+	info := obj.(*TokenInfo)
+
+	info.Mutex.Lock()
+	defer info.Mutex.Unlock()
+	return info.Token, true
 }
 
 func MarkTokenAsUnused(token int) {
@@ -122,21 +163,40 @@ func MarkTokenAsUnused(token int) {
 		info.Mutex.Lock()
 		defer info.Mutex.Unlock()
 		info.Used = false
+		info.Session = nil // The next server using the token gets a session of its own
+	}
+}
+
+// Helper function for marking a token as used by a server (when restoring servers)
+func markTokenAsUsed(token int) {
+	if obj, ok := tokensMap.Load(token); ok {
+		info := obj.(*TokenInfo)
+
+		info.Mutex.Lock()
+		defer info.Mutex.Unlock()
+		info.Used = true
 	}
 }
 
 // Always lock the token counter mutex before
 func saveToTokens() {
-	foundTokens := []Token{}
+	foundTokens := []storedToken{}
 	tokensMap.Range(func(key, value any) bool {
 		token := value.(*TokenInfo)
 
 		token.Mutex.Lock()
 		defer token.Mutex.Unlock()
 
-		foundTokens = append(foundTokens, token.Token)
+		id := token.Id
+		foundTokens = append(foundTokens, storedToken{
+			ID:    &id,
+			Token: token.Token,
+		})
 		return true
 	})
+	slices.SortFunc(foundTokens, func(a, b storedToken) int {
+		return *a.ID - *b.ID
+	})
 
 	tokensFile := path.Join(os.Getenv("TOKEN_FILE_LOCATION"), TokenFileName)
 
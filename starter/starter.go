@@ -1,7 +1,11 @@
 package starter
 
 import (
+	"context"
+	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/Liphium/hytale-matchmaking/routes"
 	"github.com/Liphium/hytale-matchmaking/service"
@@ -12,14 +16,28 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// Start the matchmaker (returns once it has been stopped using SIGINT or SIGTERM)
 func Start() {
+	StartWithShutdown(func() {})
+}
+
+// Same as Start, but shutdown runs first when stopping (the state is saved one last time after it)
+func StartWithShutdown(shutdown func()) {
 	godotenv.Load()
 	service.LoadTokens()
+	service.SetupAlerts()
+	service.SetupState()
+	service.StartTokenRefresher()
+	service.LoadRatings()
+	service.LoadTicketKey()
+	service.SetupRegions()
+	service.StartQueueProcessor()
 
 	app := fiber.New()
 
 	app.Use(cors.New())
 	app.Use(logger.New())
+	app.Use(service.MetricsMiddleware())
 
 	// Setup all the routes
 	app.Route("/api", routes.SetupRoutes)
@@ -29,6 +47,9 @@ func Start() {
 		return c.SendString("Hello from Hytale Matchmaking!")
 	})
 
+	// Metrics for Prometheus (the scraper has to send the admin credential as the Credential header)
+	app.Get("/metrics", service.AuthMiddleware(service.RoleAdmin), service.MetricsHandler())
+
 	// Add a startup hook to notify Magic of the app start
 	app.Hooks().OnListen(func(listenData fiber.ListenData) error {
 		if fiber.IsChild() {
@@ -40,5 +61,18 @@ func Start() {
 		return nil
 	})
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown()
+		app.Shutdown()
+	}()
+
 	app.Listen(os.Getenv("LISTEN"))
+
+	// The periodic snapshots stop with the process, everything since the last one would be lost otherwise
+	if err := service.SaveState(); err != nil {
+		log.Println("Couldn't save state:", err)
+	}
 }
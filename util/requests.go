@@ -259,3 +259,35 @@ func ServerPath(server string, path string) string {
 	}
 	return server + path
 }
+
+// Send a post request to any URL without caring about the response body (any 2xx status is fine)
+func PostWithoutResponse(url string, body any, headers Headers) error {
+
+	// Encode body to JSON
+	byteBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(byteBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return HTTPError{
+			StatusCode: res.StatusCode,
+		}
+	}
+	return nil
+}
@@ -5,6 +5,9 @@ import (
 	"log"
 	"runtime/debug"
 	"testing"
+	"time"
+
+	"github.com/Liphium/hytale-matchmaking/service"
 )
 
 // Unmarshal interface to struct
@@ -52,3 +55,23 @@ func PrintError(t *testing.T, kind string, err error) {
 		}
 	}
 }
+
+// Wait for an event of a certain type (other events are skipped, fails the test after a second or when the stream is closed)
+func WaitForEvent(t *testing.T, events <-chan service.Event, eventType string) service.Event {
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatal("event stream closed before a", eventType, "event was received")
+				return service.Event{}
+			}
+			if event.Type == eventType {
+				return event
+			}
+		case <-timeout:
+			t.Fatal("no", eventType, "event received")
+			return service.Event{}
+		}
+	}
+}